@@ -26,8 +26,8 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// PricesChart returns a chart given a slice of prices
-func PricesChart(title string, times *[12]time.Time, prices *[12]uint32, ownedBells uint32, forecast *Forecast, location *time.Location) (*bytes.Buffer, error) {
+// PricesChart returns a chart given a slice of prices, labeled in a language
+func PricesChart(texts *Texts, title string, times *[12]time.Time, prices *[12]uint32, ownedBells uint32, forecast *Forecast, location *time.Location) (*bytes.Buffer, error) {
 	title += fmt.Sprintf(" | %s - %s", times[0].Format("2006-01-02"), times[len(times)-1].Format("2006-01-02"))
 
 	// Graph series slice
@@ -55,16 +55,28 @@ func PricesChart(title string, times *[12]time.Time, prices *[12]uint32, ownedBe
 
 	// Create prediction series if any
 	if forecast != nil && len(forecast.Patterns) > 0 {
-		predMinSeries := chart.TimeSeries{
+		// Shaded band between the 10th and 90th percentiles
+		bandSeries := &PriceBandSeries{
 			Style: chart.Style{
-				StrokeColor:     chart.ColorOrange,
-				StrokeDashArray: []float64{5.0, 5.0},
+				StrokeColor: chart.ColorOrange.WithAlpha(64),
+				FillColor:   chart.ColorOrange.WithAlpha(48),
+			},
+			XValues:  times[:],
+			Y1Values: []float64{},
+			Y2Values: []float64{},
+		}
+
+		// Median line
+		predMedianSeries := chart.TimeSeries{
+			Style: chart.Style{
+				StrokeColor: chart.ColorOrange,
 			},
 			XValues: times[:],
 			YValues: []float64{},
 		}
 
-		predMaxSeries := chart.TimeSeries{
+		// Dashed line for the expected price
+		predExpectedSeries := chart.TimeSeries{
 			Style: chart.Style{
 				StrokeColor:     chart.ColorOrange,
 				StrokeDashArray: []float64{5.0, 5.0},
@@ -73,13 +85,16 @@ func PricesChart(title string, times *[12]time.Time, prices *[12]uint32, ownedBe
 			YValues: []float64{},
 		}
 
-		for _, v := range forecast.MaxMin {
-			predMinSeries.YValues = append(predMinSeries.YValues, float64(v.Min))
-			predMaxSeries.YValues = append(predMaxSeries.YValues, float64(v.Max))
+		for _, v := range forecast.Bands {
+			bandSeries.Y1Values = append(bandSeries.Y1Values, float64(v.P90))
+			bandSeries.Y2Values = append(bandSeries.Y2Values, float64(v.P10))
+			predMedianSeries.YValues = append(predMedianSeries.YValues, float64(v.P50))
+			predExpectedSeries.YValues = append(predExpectedSeries.YValues, v.Expected)
 		}
 
-		graphSeries = append(graphSeries, predMinSeries)
-		graphSeries = append(graphSeries, predMaxSeries)
+		graphSeries = append(graphSeries, bandSeries)
+		graphSeries = append(graphSeries, predMedianSeries)
+		graphSeries = append(graphSeries, predExpectedSeries)
 	}
 
 	// Create price series
@@ -146,9 +161,9 @@ func PricesChart(title string, times *[12]time.Time, prices *[12]uint32, ownedBe
 	}
 
 	if forecast != nil && len(forecast.Patterns) > 0 {
-		for _, price := range forecast.MaxMin {
-			YRange.Max = math.Max(YRange.Max, float64(price.Max))
-			YRange.Min = math.Min(YRange.Min, float64(price.Min))
+		for _, price := range forecast.Bands {
+			YRange.Max = math.Max(YRange.Max, float64(price.P90))
+			YRange.Min = math.Min(YRange.Min, float64(price.P10))
 		}
 	}
 
@@ -167,7 +182,7 @@ func PricesChart(title string, times *[12]time.Time, prices *[12]uint32, ownedBe
 	// Fill annotations and ticks
 	for i := 0; i < len(times); i++ {
 		ticks[i].Value = chart.TimeToFloat64(times[i])
-		ticks[i].Label = TimeToShortDayAMPM(times[i])
+		ticks[i].Label = TimeToShortDayAMPM(texts, times[i])
 	}
 
 	// Create the graph
@@ -213,8 +228,61 @@ func PricesChart(title string, times *[12]time.Time, prices *[12]uint32, ownedBe
 	return buffer, err
 }
 
-// TimeToShortDayAMPM prints the name of the weekday plus AM or PM
-func TimeToShortDayAMPM(t time.Time) string {
+// PriceBandSeries is a series that draws a shaded band between two lines
+type PriceBandSeries struct {
+	Name     string
+	Style    chart.Style
+	YAxis    chart.YAxisType
+	XValues  []time.Time
+	Y1Values []float64
+	Y2Values []float64
+}
+
+// GetName returns the name of the series.
+func (pbs *PriceBandSeries) GetName() string {
+	return pbs.Name
+}
+
+// GetStyle returns the style of the series.
+func (pbs *PriceBandSeries) GetStyle() chart.Style {
+	return pbs.Style
+}
+
+// GetYAxis returns which YAxis the series draws on.
+func (pbs *PriceBandSeries) GetYAxis() chart.YAxisType {
+	return pbs.YAxis
+}
+
+// Len returns the number of elements in the series.
+func (pbs *PriceBandSeries) Len() int {
+	return len(pbs.XValues)
+}
+
+// GetBoundedValues gets the upper and lower values at a given index.
+func (pbs *PriceBandSeries) GetBoundedValues(index int) (x, y1, y2 float64) {
+	return chart.TimeToFloat64(pbs.XValues[index]), pbs.Y1Values[index], pbs.Y2Values[index]
+}
+
+// Render renders the series.
+func (pbs *PriceBandSeries) Render(r chart.Renderer, canvasBox chart.Box, xrange, yrange chart.Range, defaults chart.Style) {
+	chart.Draw.BoundedSeries(r, canvasBox, xrange, yrange, pbs.Style.InheritFrom(defaults), pbs)
+}
+
+// Validate validates the series.
+func (pbs *PriceBandSeries) Validate() error {
+	if len(pbs.XValues) == 0 {
+		return fmt.Errorf("price band series must have xvalues set")
+	}
+
+	if len(pbs.XValues) != len(pbs.Y1Values) || len(pbs.XValues) != len(pbs.Y2Values) {
+		return fmt.Errorf("price band series must have the same number of xvalues and yvalues")
+	}
+
+	return nil
+}
+
+// TimeToShortDayAMPM prints the name of the weekday in a language plus AM or PM
+func TimeToShortDayAMPM(texts *Texts, t time.Time) string {
 	return texts.DaysShort[t.Weekday()] + " " + t.Format("PM")
 }
 
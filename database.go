@@ -47,6 +47,13 @@ func OpenDB(host string, port string, user string, password string, dbname strin
 	return &Database{DB: db}, nil
 }
 
+// Transaction runs a function with a Database using a transaction, it is committed if the function doesn't fail
+func (d *Database) Transaction(fn func(tx *Database) error) error {
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(&Database{DB: tx})
+	})
+}
+
 // ZerologGorm is a simple custom logger using Zerolog for GORM
 type ZerologGorm struct{}
 
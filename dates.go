@@ -0,0 +1,138 @@
+// Copyright (c) 2020 Sergio Conde skgsergio@gmail.com
+//
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU General Public License as published by the Free Software
+// Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY
+// WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+// PARTICULAR PURPOSE. See the GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along with
+// this program. If not, see <https://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/now"
+)
+
+var (
+	// accentsReplacer removes the accents so users can skip them when typing dates
+	accentsReplacer = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+)
+
+// normalizeDateWord returns a date word lowercased and without accents or trailing dots
+func normalizeDateWord(s string) string {
+	return strings.TrimSuffix(accentsReplacer.Replace(strings.ToLower(s)), ".")
+}
+
+// dateWordIn returns if a normalized date word is in a list of date words
+func dateWordIn(word string, words []string) bool {
+	for _, w := range words {
+		if word == normalizeDateWord(w) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// currentHalfDay returns the half-day the time belongs to, set to 00:00:00 (AM) or 12:00:00 (PM)
+func currentHalfDay(nowCfg *now.Config, t time.Time) time.Time {
+	amDate := nowCfg.With(t.In(nowCfg.TimeLocation)).BeginningOfDay()
+	pmDate := amDate.Add(time.Hour * 12)
+
+	if t.Before(pmDate) {
+		return amDate
+	}
+
+	return pmDate
+}
+
+// weekHalfDay returns a half-day of the week the time belongs to by its index, Monday AM is 0. It uses calendar days
+// so daylight saving time changes don't shift it.
+func weekHalfDay(nowCfg *now.Config, t time.Time, halfDay int) time.Time {
+	date := nowCfg.With(t.In(nowCfg.TimeLocation)).BeginningOfWeek().AddDate(0, 0, 1+halfDay/2)
+
+	if halfDay%2 == 1 {
+		date = time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, date.Location())
+	}
+
+	return date
+}
+
+// parseHalfDay parses an user input date, either using the AM/PM format or in any of the bot languages like "monday am"
+// or "yesterday pm", days of the week are relative to the week the time belongs to
+func parseHalfDay(s string, nowCfg *now.Config, t time.Time) (time.Time, error) {
+	if date, err := nowCfg.Parse(s); err == nil {
+		return date, nil
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return time.Time{}, ErrDateParse
+	}
+
+	dayWord := normalizeDateWord(fields[0])
+	halfWord := normalizeDateWord(fields[1])
+
+	// Try the default language first
+	if date, ok := parseHalfDayWords(defaultTexts, dayWord, halfWord, nowCfg, t); ok {
+		return date, nil
+	}
+
+	for _, texts := range langs {
+		if date, ok := parseHalfDayWords(texts, dayWord, halfWord, nowCfg, t); ok {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, ErrDateParse
+}
+
+// parseHalfDayWords parses normalized day and half of the day words in a language
+func parseHalfDayWords(texts *Texts, dayWord string, halfWord string, nowCfg *now.Config, t time.Time) (time.Time, bool) {
+	// Get the day
+	local := nowCfg.With(t.In(nowCfg.TimeLocation))
+	today := local.BeginningOfDay()
+
+	var (
+		day   time.Time
+		found bool
+	)
+
+	switch {
+	case dateWordIn(dayWord, texts.Dates.Today):
+		day, found = today, true
+	case dateWordIn(dayWord, texts.Dates.Yesterday):
+		day, found = today.AddDate(0, 0, -1), true
+	default:
+		for weekday := range texts.Days {
+			if dayWord == normalizeDateWord(texts.Days[weekday]) || dayWord == normalizeDateWord(texts.DaysShort[weekday]) {
+				offset := (weekday - int(nowCfg.WeekStartDay) + 7) % 7
+				day, found = local.BeginningOfWeek().AddDate(0, 0, offset), true
+				break
+			}
+		}
+	}
+
+	if !found {
+		return time.Time{}, false
+	}
+
+	// Get the half of the day
+	switch {
+	case dateWordIn(halfWord, texts.Dates.AM):
+		return day, true
+	case dateWordIn(halfWord, texts.Dates.PM):
+		return day.Add(time.Hour * 12), true
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,235 @@
+// Copyright (c) 2020 Sergio Conde skgsergio@gmail.com
+//
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU General Public License as published by the Free Software
+// Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY
+// WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+// PARTICULAR PURPOSE. See the GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along with
+// this program. If not, see <https://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	exportFormatCSV  = "csv"
+	exportFormatJSON = "json"
+)
+
+var (
+	// ErrExportFormat is returned when the export format is unknown
+	ErrExportFormat = errors.New("unknown export format")
+
+	// ErrExportGroup is returned when the group to export is missing or doesn't exist
+	ErrExportGroup = errors.New("unknown group to export")
+)
+
+// ExportRecord is a Price, Owned or IslandPrice of a GroupExport, the date is in the group timezone
+type ExportRecord struct {
+	Record   AuditRecord `json:"record"`
+	UserID   int64       `json:"user_id"`
+	User     string      `json:"user"`
+	Username string      `json:"username"`
+	Date     string      `json:"date"`
+	Units    uint32      `json:"units"`
+	Bells    uint32      `json:"bells"`
+	FirstBuy bool        `json:"first_buy"`
+}
+
+// GroupExport is the full history of prices, owned turnips and island prices of a Group
+type GroupExport struct {
+	GroupID int64          `json:"group_id"`
+	Title   string         `json:"title"`
+	TZ      string         `json:"tz"`
+	Records []ExportRecord `json:"records"`
+}
+
+// validExportFormat returns if an export format is known
+func validExportFormat(format string) bool {
+	return format == exportFormatCSV || format == exportFormatJSON
+}
+
+// NewGroupExport returns the GroupExport of a Group
+func NewGroupExport(g *Group) (*GroupExport, error) {
+	nowCfg, err := g.NowConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	prices, err := db.getGroupPrices(g)
+	if err != nil {
+		return nil, err
+	}
+
+	owneds, err := db.getGroupAllOwneds(g)
+	if err != nil {
+		return nil, err
+	}
+
+	islandPrices, err := db.getGroupIslandPrices(g)
+	if err != nil {
+		return nil, err
+	}
+
+	e := GroupExport{GroupID: g.ID, Title: g.Title, TZ: g.TZ, Records: []ExportRecord{}}
+
+	record := func(kind AuditRecord, u *User, date time.Time) ExportRecord {
+		return ExportRecord{
+			Record:   kind,
+			UserID:   u.ID,
+			User:     u.Name(),
+			Username: u.Username,
+			Date:     date.In(nowCfg.TimeLocation).Format(time.RFC3339),
+		}
+	}
+
+	for _, price := range prices {
+		r := record(AuditPrice, &price.User, price.Date)
+		r.Bells = price.Bells
+		e.Records = append(e.Records, r)
+	}
+
+	for _, owned := range owneds {
+		r := record(AuditOwned, &owned.User, owned.Date)
+		r.Units = owned.Units
+		r.Bells = owned.Bells
+		e.Records = append(e.Records, r)
+	}
+
+	for _, islandPrice := range islandPrices {
+		r := record(AuditIslandPrice, &islandPrice.User, islandPrice.Date)
+		r.Bells = islandPrice.Bells
+		r.FirstBuy = islandPrice.FirstBuy
+		e.Records = append(e.Records, r)
+	}
+
+	return &e, nil
+}
+
+// FileName returns the name of the export file in a format
+func (e *GroupExport) FileName(format string) string {
+	return fmt.Sprintf("mercanabo_%d_%s.%s", e.GroupID, time.Now().Format("20060102"), format)
+}
+
+// Write writes the export in a format, CSV has one row per record
+func (e *GroupExport) Write(w io.Writer, format string) error {
+	switch format {
+	case exportFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(e)
+
+	case exportFormatCSV:
+		writer := csv.NewWriter(w)
+
+		err := writer.Write([]string{"record", "user_id", "user", "username", "date", "units", "bells", "first_buy"})
+		if err != nil {
+			return err
+		}
+
+		for _, r := range e.Records {
+			err = writer.Write([]string{
+				string(r.Record),
+				strconv.FormatInt(r.UserID, 10),
+				r.User,
+				r.Username,
+				r.Date,
+				strconv.FormatUint(uint64(r.Units), 10),
+				strconv.FormatUint(uint64(r.Bells), 10),
+				strconv.FormatBool(r.FirstBuy),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		writer.Flush()
+
+		return writer.Error()
+	}
+
+	return ErrExportFormat
+}
+
+// runExport runs the export subcommand, writing the export of a group to a file or the standard output
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", exportFormatCSV, "export format: csv or json")
+	output := flags.String("output", "", "output file, standard output if empty")
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s export [options] <group id>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+
+		return err
+	}
+
+	if !validExportFormat(*format) {
+		flags.Usage()
+		return ErrExportFormat
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return ErrExportGroup
+	}
+
+	groupID, err := parseInt64(flags.Arg(0))
+	if err != nil {
+		flags.Usage()
+		return ErrExportGroup
+	}
+
+	group, err := db.GetGroupByID(groupID)
+	if err != nil {
+		return err
+	}
+
+	if group == nil {
+		return ErrExportGroup
+	}
+
+	export, err := NewGroupExport(group)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return export.Write(os.Stdout, *format)
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+
+	if err = export.Write(file, *format); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
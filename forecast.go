@@ -48,24 +48,35 @@ type DayPrice struct {
 	Max uint32
 }
 
+// DayBands represents the probability weighted expected price and percentiles in a day
+type DayBands struct {
+	Expected float64
+	P10      uint32
+	P50      uint32
+	P90      uint32
+}
+
 // Pattern represents a pattern following the ACNH algorithm
 type Pattern struct {
-	Type   PatternType
-	Prices [12]DayPrice
+	Type        PatternType
+	Prices      [12]DayPrice
+	Probability float64
 }
 
 // Forecast represents a predicion of the Stalk Market
 type Forecast struct {
 	sellPrice uint32
 	buyPrices [12]uint32
+	firstBuy  bool
 
 	Patterns      []Pattern
 	MaxMin        [12]DayPrice
+	Bands         [12]DayBands
 	Probabilities map[PatternType]float64
 }
 
-// NewForecast returns a Forecasts
-func NewForecast(sellPrice uint32, buyPrices [12]uint32, previousWeek *Forecast) (*Forecast, error) {
+// NewForecast returns a Forecasts, if firstBuy is set only the small spike pattern is considered as the game forces it
+func NewForecast(sellPrice uint32, buyPrices [12]uint32, previousWeek *Forecast, firstBuy bool) (*Forecast, error) {
 	if sellPrice < 90 || sellPrice > 110 {
 		return nil, ErrSellPrice
 	}
@@ -79,6 +90,7 @@ func NewForecast(sellPrice uint32, buyPrices [12]uint32, previousWeek *Forecast)
 	f := Forecast{
 		sellPrice: sellPrice,
 		buyPrices: buyPrices,
+		firstBuy:  firstBuy,
 	}
 
 	f.runForecast(previousWeek)
@@ -86,6 +98,79 @@ func NewForecast(sellPrice uint32, buyPrices [12]uint32, previousWeek *Forecast)
 	return &f, nil
 }
 
+// NewKnownForecast returns a Forecast where the given pattern is certain, useful as previous week of a new Forecast
+func NewKnownForecast(pattern PatternType) *Forecast {
+	return &Forecast{
+		Patterns:      []Pattern{},
+		Probabilities: map[PatternType]float64{pattern: 1},
+	}
+}
+
+// MaxFrom returns the expected max price from the given half day until the end of the week and the chance of any price
+// in those half days exceeding the given price, prices in a pattern range are considered equally likely and independent
+func (f *Forecast) MaxFrom(halfDay int, price uint32) (float64, float64) {
+	var expected, chance float64 = 0, 0
+
+	if halfDay < 0 {
+		halfDay = 0
+	}
+
+	for _, pattern := range f.Patterns {
+		var patMax float64 = 0
+		var notExceeding float64 = 1
+
+		for i := halfDay; i < len(pattern.Prices); i++ {
+			dp := pattern.Prices[i]
+
+			if dp.Min > dp.Max {
+				continue
+			}
+
+			patMax = math.Max(patMax, float64(dp.Min+dp.Max)/2)
+
+			if price < dp.Min {
+				notExceeding = 0
+			} else if price < dp.Max {
+				notExceeding *= float64(price-dp.Min+1) * priceLikelihood(dp.Min, dp.Max)
+			}
+		}
+
+		expected += pattern.Probability * patMax
+		chance += pattern.Probability * (1 - notExceeding)
+	}
+
+	return expected, chance
+}
+
+// PeakFrom returns the half day with the highest expected price from the given half day until the end of the week
+func (f *Forecast) PeakFrom(halfDay int) int {
+	if halfDay < 0 {
+		halfDay = 0
+	}
+
+	peak := halfDay
+	for i := halfDay; i < len(f.Bands); i++ {
+		if f.Bands[i].Expected > f.Bands[peak].Expected {
+			peak = i
+		}
+	}
+
+	return peak
+}
+
+// CertainPattern returns the pattern type if it is the only one possible
+func (f *Forecast) CertainPattern() (PatternType, bool) {
+	if len(f.Probabilities) != 1 {
+		return 0, false
+	}
+
+	for pattern := range f.Probabilities {
+		return pattern, true
+	}
+
+	return 0, false
+}
+
 // Common operations
 
 // minRate returns the minimum rate vs the sell price for a half day
@@ -108,12 +193,34 @@ func (f *Forecast) maxRatePrice(r float64) uint32 {
 	return uint32(math.Ceil(r * float64(f.sellPrice)))
 }
 
+// priceLikelihood returns the likelihood of a known price given the predicted range, assuming every price in it is equally likely
+func priceLikelihood(minPred, maxPred uint32) float64 {
+	return 1 / float64(maxPred-minPred+1)
+}
+
+// pmfPercentile returns the lowest price whose cumulative probability reaches the given percentile
+func pmfPercentile(pmf []float64, minPrice uint32, percentile float64) uint32 {
+	var cumulative float64 = 0
+
+	for i, p := range pmf {
+		cumulative += p
+
+		if cumulative >= percentile {
+			return minPrice + uint32(i)
+		}
+	}
+
+	return minPrice + uint32(len(pmf)-1)
+}
+
 // Random pattern functions
 
 // genRandomPattern returns a random pattern following the given halfs for each phase
 func (f *Forecast) genRandomPattern(inc1Halfs, dec1Halfs, inc2Halfs, dec2Halfs, inc3Halfs uint8) (*Pattern, error) {
+	// Phase lengths chances: dec1 is 2 or 3, inc1 is between 0 and 6 and inc3 between 0 and 7 - inc1 - 1
 	pattern := Pattern{
-		Type: Random,
+		Type:        Random,
+		Probability: 1.0 / 2 / 7 / float64(7-inc1Halfs),
 	}
 
 	// Check constraints
@@ -152,6 +259,8 @@ func (f *Forecast) genRandomPattern(inc1Halfs, dec1Halfs, inc2Halfs, dec2Halfs,
 				return nil, ErrNoMatch
 			}
 
+			pattern.Probability *= priceLikelihood(minPred, maxPred)
+
 			minPred = f.buyPrices[i]
 			maxPred = f.buyPrices[i]
 		}
@@ -176,6 +285,8 @@ func (f *Forecast) genRandomPattern(inc1Halfs, dec1Halfs, inc2Halfs, dec2Halfs,
 				return nil, ErrNoMatch
 			}
 
+			pattern.Probability *= priceLikelihood(minPred, maxPred)
+
 			minPred = f.buyPrices[i]
 			maxPred = f.buyPrices[i]
 
@@ -203,6 +314,8 @@ func (f *Forecast) genRandomPattern(inc1Halfs, dec1Halfs, inc2Halfs, dec2Halfs,
 				return nil, ErrNoMatch
 			}
 
+			pattern.Probability *= priceLikelihood(minPred, maxPred)
+
 			minPred = f.buyPrices[i]
 			maxPred = f.buyPrices[i]
 		}
@@ -227,6 +340,8 @@ func (f *Forecast) genRandomPattern(inc1Halfs, dec1Halfs, inc2Halfs, dec2Halfs,
 				return nil, ErrNoMatch
 			}
 
+			pattern.Probability *= priceLikelihood(minPred, maxPred)
+
 			minPred = f.buyPrices[i]
 			maxPred = f.buyPrices[i]
 
@@ -254,6 +369,8 @@ func (f *Forecast) genRandomPattern(inc1Halfs, dec1Halfs, inc2Halfs, dec2Halfs,
 				return nil, ErrNoMatch
 			}
 
+			pattern.Probability *= priceLikelihood(minPred, maxPred)
+
 			minPred = f.buyPrices[i]
 			maxPred = f.buyPrices[i]
 		}
@@ -286,8 +403,10 @@ func (f *Forecast) genRandomPatterns() {
 
 // genBigSpikePattern returns a big peak pattern given a spike start
 func (f *Forecast) genBigSpikePattern(spikeStart uint8) (*Pattern, error) {
+	// Spike start chance: it's between 1 and 7
 	pattern := Pattern{
-		Type: BigSpike,
+		Type:        BigSpike,
+		Probability: 1.0 / 7,
 	}
 
 	// Check constraints
@@ -308,6 +427,8 @@ func (f *Forecast) genBigSpikePattern(spikeStart uint8) (*Pattern, error) {
 				return nil, ErrNoMatch
 			}
 
+			pattern.Probability *= priceLikelihood(minPred, maxPred)
+
 			minPred = f.buyPrices[i]
 			maxPred = f.buyPrices[i]
 
@@ -337,6 +458,8 @@ func (f *Forecast) genBigSpikePattern(spikeStart uint8) (*Pattern, error) {
 				return nil, ErrNoMatch
 			}
 
+			pattern.Probability *= priceLikelihood(minPred, maxPred)
+
 			minPred = f.buyPrices[i]
 			maxPred = f.buyPrices[i]
 
@@ -358,6 +481,8 @@ func (f *Forecast) genBigSpikePattern(spikeStart uint8) (*Pattern, error) {
 				return nil, ErrNoMatch
 			}
 
+			pattern.Probability *= priceLikelihood(minPred, maxPred)
+
 			minPred = f.buyPrices[i]
 			maxPred = f.buyPrices[i]
 
@@ -388,7 +513,8 @@ func (f *Forecast) genBigSpikePatterns() {
 // genFallingPattern returns falling pattern
 func (f *Forecast) genFallingPattern() (*Pattern, error) {
 	pattern := Pattern{
-		Type: Falling,
+		Type:        Falling,
+		Probability: 1.0,
 	}
 
 	minRate := 0.85
@@ -403,6 +529,8 @@ func (f *Forecast) genFallingPattern() (*Pattern, error) {
 				return nil, ErrNoMatch
 			}
 
+			pattern.Probability *= priceLikelihood(minPred, maxPred)
+
 			minPred = f.buyPrices[i]
 			maxPred = f.buyPrices[i]
 
@@ -435,8 +563,10 @@ func (f *Forecast) genFallingPatterns() {
 
 // genSmallSpikePattern returns a small peak pattern given a spike start
 func (f *Forecast) genSmallSpikePattern(spikeStart uint8) (*Pattern, error) {
+	// Spike start chance: it's between 0 and 7
 	pattern := Pattern{
-		Type: SmallSpike,
+		Type:        SmallSpike,
+		Probability: 1.0 / 8,
 	}
 
 	// Check constraints
@@ -457,6 +587,8 @@ func (f *Forecast) genSmallSpikePattern(spikeStart uint8) (*Pattern, error) {
 				return nil, ErrNoMatch
 			}
 
+			pattern.Probability *= priceLikelihood(minPred, maxPred)
+
 			minPred = f.buyPrices[i]
 			maxPred = f.buyPrices[i]
 
@@ -501,6 +633,8 @@ func (f *Forecast) genSmallSpikePattern(spikeStart uint8) (*Pattern, error) {
 				return nil, ErrNoMatch
 			}
 
+			pattern.Probability *= priceLikelihood(minPred, maxPred)
+
 			minPred = f.buyPrices[i]
 			maxPred = f.buyPrices[i]
 		}
@@ -524,6 +658,8 @@ func (f *Forecast) genSmallSpikePattern(spikeStart uint8) (*Pattern, error) {
 				return nil, ErrNoMatch
 			}
 
+			pattern.Probability *= priceLikelihood(minPred, maxPred)
+
 			minPred = f.buyPrices[i]
 			maxPred = f.buyPrices[i]
 
@@ -556,17 +692,20 @@ func (f *Forecast) genSmallSpikePatterns() {
 	}
 }
 
-// runForecast generates all patterns that could match and gets the max and min possible price per day
+// runForecast generates all patterns that could match, gets the max and min possible price per day, weights the patterns by their likelihood and calcs the price bands
 func (f *Forecast) runForecast(previousWeek *Forecast) {
 	// Make sure everything is empty
 	f.Patterns = []Pattern{}
 	f.MaxMin = [12]DayPrice{}
+	f.Bands = [12]DayBands{}
 	f.Probabilities = map[PatternType]float64{}
 
-	// Generate all patterns
-	f.genRandomPatterns()
-	f.genBigSpikePatterns()
-	f.genFallingPatterns()
+	// Generate all patterns, the first week buying in the island is always a small spike
+	if !f.firstBuy {
+		f.genRandomPatterns()
+		f.genBigSpikePatterns()
+		f.genFallingPatterns()
+	}
 	f.genSmallSpikePatterns()
 
 	if len(f.Patterns) == 0 {
@@ -589,29 +728,73 @@ func (f *Forecast) runForecast(previousWeek *Forecast) {
 		}
 	}
 
-	// Calc probabilities
-	if previousWeek != nil {
+	// Calc pattern types chances given the previous week
+	typeProbs := map[PatternType]float64{}
+
+	if f.firstBuy {
+		typeProbs[SmallSpike] = 1
+	} else if previousWeek != nil && len(previousWeek.Probabilities) > 0 {
 		for pattern := range patterns {
 			for prevPattern, prevProb := range previousWeek.Probabilities {
-				f.Probabilities[pattern] += (ProbabilityTable[prevPattern][pattern] * prevProb)
+				typeProbs[pattern] += (ProbabilityTable[prevPattern][pattern] * prevProb)
 			}
 		}
 	} else {
 		for pattern := range patterns {
 			for _, patProb := range ProbabilityTable {
-				f.Probabilities[pattern] += patProb[pattern]
+				typeProbs[pattern] += patProb[pattern]
 			}
 		}
 	}
 
-	// Normalize probabilities
+	// Weight each pattern by its type chance and sum them up by type
 	var total float64 = 0
 
-	for _, p := range f.Probabilities {
-		total += p
+	for i := range f.Patterns {
+		f.Patterns[i].Probability *= typeProbs[f.Patterns[i].Type]
+		f.Probabilities[f.Patterns[i].Type] += f.Patterns[i].Probability
+		total += f.Patterns[i].Probability
+	}
+
+	// Normalize probabilities
+	if total == 0 {
+		return
+	}
+
+	for i := range f.Patterns {
+		f.Patterns[i].Probability /= total
 	}
 
 	for p := range f.Probabilities {
 		f.Probabilities[p] /= total
 	}
+
+	f.calcBands()
+}
+
+// calcBands calculates the expected price and the percentiles for each half day mixing all the weighted patterns
+func (f *Forecast) calcBands() {
+	for i := range f.Bands {
+		// Probability of each price in the half day, prices in a pattern range are considered equally likely
+		pmf := make([]float64, f.MaxMin[i].Max-f.MaxMin[i].Min+1)
+
+		for _, pattern := range f.Patterns {
+			dp := pattern.Prices[i]
+
+			if dp.Min > dp.Max {
+				continue
+			}
+
+			priceProb := pattern.Probability * priceLikelihood(dp.Min, dp.Max)
+			for price := dp.Min; price <= dp.Max; price++ {
+				pmf[price-f.MaxMin[i].Min] += priceProb
+			}
+
+			f.Bands[i].Expected += pattern.Probability * float64(dp.Min+dp.Max) / 2
+		}
+
+		f.Bands[i].P10 = pmfPercentile(pmf, f.MaxMin[i].Min, 0.1)
+		f.Bands[i].P50 = pmfPercentile(pmf, f.MaxMin[i].Min, 0.5)
+		f.Bands[i].P90 = pmfPercentile(pmf, f.MaxMin[i].Min, 0.9)
+	}
 }
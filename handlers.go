@@ -16,8 +16,14 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"html"
+	"io"
 	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -28,16 +34,188 @@ import (
 
 const (
 	tzListURL = "https://en.wikipedia.org/wiki/List_of_tz_database_time_zones"
+
+	sellHalfDayBtn = "sell_half_day"
+	sellConfirmBtn = "sell_confirm"
+	sellCancelBtn  = "sell_cancel"
+
+	historyEntries = 20
+
+	importMaxSize = 1 << 20
 )
 
+// patternKeys returns the pattern keys joined to be shown as parameters
+func patternKeys(texts *Texts) string {
+	return strings.Join([]string{
+		texts.Patterns.Random.Key,
+		texts.Patterns.BigSpike.Key,
+		texts.Patterns.Falling.Key,
+		texts.Patterns.SmallSpike.Key,
+	}, "|")
+}
+
+// weekPricesText returns the prices of a week, one half-day per line
+func weekPricesText(texts *Texts, week *UserWeek) string {
+	lines := []string{}
+
+	for i, price := range week.Prices {
+		if price == 0 {
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("<code>%s</code>: <b>%v</b> %s", TimeToShortDayAMPM(texts, week.Times[i]), price, texts.Bells))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// weekPatternsText returns the matching patterns of a week and their probabilities
+func weekPatternsText(texts *Texts, week *UserWeek) string {
+	if !week.HasIslandPrice() {
+		return texts.Patterns.NoIslandPrice
+	}
+
+	if len(week.Forecast.Patterns) == 0 {
+		return texts.Patterns.Unknown
+	}
+
+	text := texts.Patterns.Matching
+
+	for pat, prob := range week.Forecast.Probabilities {
+		pName, pDesc := texts.PatternText(pat)
+
+		text += fmt.Sprintf("\n- <b>%s</b> <i>(%.2f%%)</i>: %s", pName, prob*100, pDesc)
+	}
+
+	return text
+}
+
+// auditValuesText returns the values of an audited record
+func auditValuesText(texts *Texts, record AuditRecord, values AuditValues) string {
+	if !values.Exists {
+		return texts.Audit.None
+	}
+
+	switch record {
+	case AuditOwned:
+		return fmt.Sprintf(texts.Audit.Units, values.Units, values.Bells)
+	case AuditIslandPrice:
+		if values.FirstBuy {
+			return fmt.Sprintf("%d (%s)", values.Bells, texts.Buy.FirstBuyFlag)
+		}
+	}
+
+	return fmt.Sprintf("%d", values.Bells)
+}
+
+// auditRecordText returns the name of an audited record, the date is only used by prices
+func auditRecordText(texts *Texts, record AuditRecord, date string) string {
+	switch record {
+	case AuditPrice:
+		return fmt.Sprintf(texts.Audit.Price, date)
+	case AuditOwned:
+		return texts.Audit.Owned
+	case AuditIslandPrice:
+		return texts.Audit.IslandPrice
+	}
+
+	return ""
+}
+
+// auditEntryText returns a change of an audited record
+func auditEntryText(texts *Texts, entry *AuditEntry, location *time.Location) string {
+	record := auditRecordText(texts, entry.Record, entry.Date.In(location).Format(timeFormatAMPM))
+
+	return fmt.Sprintf(texts.Audit.Change, record, auditValuesText(texts, entry.Record, entry.Old), auditValuesText(texts, entry.Record, entry.New))
+}
+
 // handleStart triggers when /start is sent on private
 func (t *Telegram) handleStart(ctx tb.Context) error {
 	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	if !m.Private() {
+		return nil
+	}
+
+	t.send(m.Chat, fmt.Sprintf(texts.Dashboard.Start, texts.Dashboard.Cmd, texts.Chart.Cmd, texts.List.Cmd, texts.Buy.Cmd, texts.Sell.Cmd))
+
+	return nil
+}
+
+// handleDashboardCmd triggers when the dashboard cmd is sent to a private chat
+func (t *Telegram) handleDashboardCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
 	if !m.Private() {
+		rm := t.reply(m, texts.Dashboard.PrivateOnly)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	groups, err := db.GetUserGroups(m.Sender)
+	if err != nil {
+		t.reply(m, texts.InternalError)
+		return nil
+	}
+
+	if len(groups) == 0 {
+		t.reply(m, texts.Dashboard.NoGroups)
+		return nil
+	}
+
+	// Without parameters list the groups
+	parameters := strings.Fields(m.Payload)
+	if len(parameters) == 0 {
+		selected, errs := db.GetUserDashboardGroup(m.Sender)
+		if errs != nil {
+			t.reply(m, texts.InternalError)
+			return nil
+		}
+
+		reply := texts.Dashboard.Groups + "\n"
+
+		for i, group := range groups {
+			reply += "\n" + fmt.Sprintf(texts.Dashboard.Group, i+1, group.Title)
+
+			if selected != nil && selected.ID == group.ID {
+				reply += texts.Dashboard.Selected
+			}
+		}
+
+		reply += fmt.Sprintf("\n\n<code>/%s %s</code>", texts.Dashboard.Cmd, texts.Dashboard.Params)
+
+		t.send(m.Chat, reply)
+		return nil
+	}
+
+	// Validate the parameters
+	index, err := parseUint32(parameters[0])
+	if len(parameters) != 1 || err != nil || index == 0 || int(index) > len(groups) {
+		t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Dashboard.Params))
+		return nil
+	}
+
+	group := groups[index-1]
+
+	err = db.ChangeUserDashboardGroup(m.Sender, group)
+	if err != nil {
+		t.reply(m, texts.InternalError)
 		return nil
 	}
 
-	t.send(m.Chat, texts.GroupOnly)
+	// Questions are for the previous group
+	t.clearPricePrompt(m)
+
+	t.reply(m, fmt.Sprintf(texts.Dashboard.Changed, group.Title))
 
 	return nil
 }
@@ -45,6 +223,8 @@ func (t *Telegram) handleStart(ctx tb.Context) error {
 // handleAddedToGroup triggers when the bot is added to a group
 func (t *Telegram) handleAddedToGroup(ctx tb.Context) error {
 	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
 	log.Info().Str("module", "telegram").Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).Msg("added to group")
 
 	// Register the group in the DB
@@ -71,12 +251,20 @@ func (t *Telegram) handleGroupMigration(ctx tb.Context) error {
 		return nil
 	}
 
+	// The group menu language is set per chat
+	group, err := db.GetGroupByID(to)
+	if err == nil && group != nil && group.Lang != "" {
+		t.setChatCommands(&tb.Chat{ID: to}, group.Lang)
+	}
+
 	return nil
 }
 
 // handleHelpCmd triggers when the help cmd is sent to a group
 func (t *Telegram) handleHelpCmd(ctx tb.Context) error {
 	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
 	if m.Private() {
 		t.send(m.Chat, texts.GroupOnly)
 		return nil
@@ -93,12 +281,25 @@ func (t *Telegram) handleHelpCmd(ctx tb.Context) error {
 		texts.Help.AvailableCmds,
 		fmt.Sprintf("\n<code>/%s</code>\n%s", texts.Help.Cmd, texts.Help.Desc),
 		fmt.Sprintf("\n<code>/%s</code>\n%s", texts.Admin.Cmd, texts.Admin.Desc),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Dashboard.Cmd, texts.Dashboard.Params, fmt.Sprintf(texts.Dashboard.Desc, texts.Chart.Cmd, texts.List.Cmd, texts.Buy.Cmd, texts.Sell.Cmd)),
 		fmt.Sprintf("\n<code>/%s</code>\n%s", texts.List.Cmd, texts.List.Desc),
 		fmt.Sprintf("\n<code>/%s</code>\n%s", texts.Chart.Cmd, texts.Chart.Desc),
+		fmt.Sprintf("\n<code>/%s</code>\n%s", texts.Advice.Cmd, texts.Advice.Desc),
+		fmt.Sprintf("\n<code>/%s</code>\n%s", texts.Islands.Cmd, texts.Islands.Desc),
+		fmt.Sprintf("\n<code>/%s</code>\n%s", texts.Leaderboard.Cmd, texts.Leaderboard.Desc),
 		fmt.Sprintf("\n<code>/%s</code>\n%s", texts.Turnips.Cmd, texts.Turnips.Desc),
-		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Buy.Cmd, texts.Buy.Params, texts.Buy.Desc),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Buy.Cmd, texts.Buy.Params, fmt.Sprintf(texts.Buy.Desc, texts.Buy.FirstBuyFlag)),
 		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.IslandPrice.Cmd, texts.IslandPrice.Params, fmt.Sprintf(texts.IslandPrice.Desc, texts.Buy.Cmd)),
 		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Sell.Cmd, texts.Sell.Params, texts.Sell.Desc),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Week.Cmd, texts.Week.Params, texts.Week.Desc),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Sold.Cmd, texts.Sold.Params, texts.Sold.Desc),
+		fmt.Sprintf("\n<code>/%s</code>\n%s", texts.Undo.Cmd, texts.Undo.Desc),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Clear.Cmd, texts.Clear.Params, texts.Clear.Desc),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Import.Cmd, texts.Import.Params, texts.Import.Desc),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.SpikeAlerts.Cmd, texts.SpikeAlerts.Params, texts.SpikeAlerts.Desc),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.LastPattern.Cmd, fmt.Sprintf(texts.LastPattern.Params, patternKeys(texts)), texts.LastPattern.Desc),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Lang.Cmd, fmt.Sprintf(texts.Lang.Params, texts.Off), texts.Lang.Desc),
+		"\n" + fmt.Sprintf(texts.Help.Reminders, texts.Reminders.Cmd, texts.BuyReminder.Cmd),
 	}
 
 	t.send(m.Chat, strings.Join(helpLines, "\n"), tb.NoPreview)
@@ -110,6 +311,8 @@ func (t *Telegram) handleHelpCmd(ctx tb.Context) error {
 // handleAdminCmd triggers when the admin cmd is sent to a group
 func (t *Telegram) handleAdminCmd(ctx tb.Context) error {
 	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
 	if m.Private() {
 		t.send(m.Chat, texts.GroupOnly)
 		return nil
@@ -140,6 +343,14 @@ func (t *Telegram) handleAdminCmd(ctx tb.Context) error {
 		texts.Admin.AvailableCmds,
 		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Delete.Cmd, texts.Delete.Params, texts.Delete.Desc),
 		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.ChangeTZ.Cmd, texts.ChangeTZ.Params, fmt.Sprintf(texts.ChangeTZ.Desc, tzListURL)),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Lang.Cmd, fmt.Sprintf(texts.Lang.Params, texts.Off), texts.Lang.AdminDesc),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Alerts.Cmd, texts.Alerts.Params, texts.Alerts.Desc),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Reminders.Cmd, texts.Reminders.Params, texts.Reminders.Desc),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.BuyReminder.Cmd, texts.BuyReminder.Params, texts.BuyReminder.Desc),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Fix.Cmd, texts.Fix.Params, texts.Fix.Desc),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Remove.Cmd, texts.Remove.Params, texts.Remove.Desc),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.History.Cmd, texts.History.Params, fmt.Sprintf(texts.History.Desc, historyEntries)),
+		fmt.Sprintf("\n<code>/%s %s</code>\n%s", texts.Export.Cmd, texts.Export.Params, texts.Export.Desc),
 	}
 
 	t.send(m.Chat, strings.Join(helpLines, "\n"), tb.NoPreview)
@@ -148,11 +359,20 @@ func (t *Telegram) handleAdminCmd(ctx tb.Context) error {
 	return nil
 }
 
-// handleBuyCmd triggers when the buy cmd is sent to a group
+// handleBuyCmd triggers when the buy cmd is sent to a group, or to a private dashboard
 func (t *Telegram) handleBuyCmd(ctx tb.Context) error {
 	m := ctx.Message()
-	if m.Private() {
-		t.send(m.Chat, texts.GroupOnly)
+	texts := t.texts(m.Chat, m.Sender)
+
+	// In private chats use the dashboard selected group
+	chat, err := t.groupChat(m)
+	if err != nil {
+		t.reply(m, texts.InternalError)
+		return nil
+	}
+
+	if chat == nil {
+		t.reply(m, fmt.Sprintf(texts.Dashboard.NoGroup, texts.Dashboard.Cmd))
 		return nil
 	}
 
@@ -165,6 +385,13 @@ func (t *Telegram) handleBuyCmd(ctx tb.Context) error {
 
 	// Validate the parameters
 	parameters := strings.Fields(m.Payload)
+
+	firstBuy := false
+	if len(parameters) > 0 && strings.EqualFold(parameters[len(parameters)-1], texts.Buy.FirstBuyFlag) {
+		firstBuy = true
+		parameters = parameters[:len(parameters)-1]
+	}
+
 	if len(parameters) != 2 && len(parameters) != 3 {
 		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Buy.Params))
 		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
@@ -195,8 +422,8 @@ func (t *Telegram) handleBuyCmd(ctx tb.Context) error {
 		}
 	}
 
-	// Store user turnips
-	newO, oldUnits, oldBells, err := db.SaveThisWeekOwned(m.Sender, m.Chat, units, bells)
+	// Store user turnips and island price
+	newO, oldUnits, oldBells, newIP, oldIslandPrice, err := db.SaveThisWeekBuy(m.Sender, chat, units, bells, islandPrice, firstBuy)
 	if err != nil {
 		rm := t.reply(m, texts.InternalError)
 		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
@@ -210,14 +437,6 @@ func (t *Telegram) handleBuyCmd(ctx tb.Context) error {
 		msgTxt1 = fmt.Sprintf(texts.Buy.Changed, units, bells, oldUnits, oldBells)
 	}
 
-	// Store island price
-	newIP, oldIslandPrice, err := db.SaveUserIslandPrice(m.Sender, m.Chat, islandPrice)
-	if err != nil {
-		rm := t.reply(m, texts.InternalError)
-		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
-		return nil
-	}
-
 	var msgTxt2 string
 	if newIP || (oldIslandPrice == islandPrice) {
 		msgTxt2 = fmt.Sprintf(texts.IslandPrice.Saved, islandPrice)
@@ -225,6 +444,10 @@ func (t *Telegram) handleBuyCmd(ctx tb.Context) error {
 		msgTxt2 = fmt.Sprintf(texts.IslandPrice.Changed, islandPrice, oldIslandPrice)
 	}
 
+	if firstBuy {
+		msgTxt2 += "\n\n" + texts.IslandPrice.FirstBuy
+	}
+
 	// Send reply
 	rm := t.reply(m, fmt.Sprintf("%s\n\n%s", msgTxt1, msgTxt2))
 	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
@@ -235,6 +458,8 @@ func (t *Telegram) handleBuyCmd(ctx tb.Context) error {
 // handleIslandPriceCmd triggers when the islandprice cmd is sent to a group
 func (t *Telegram) handleIslandPriceCmd(ctx tb.Context) error {
 	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
 	if m.Private() {
 		t.send(m.Chat, texts.GroupOnly)
 		return nil
@@ -249,6 +474,13 @@ func (t *Telegram) handleIslandPriceCmd(ctx tb.Context) error {
 
 	// Validate the parameters
 	parameters := strings.Fields(m.Payload)
+
+	firstBuy := false
+	if len(parameters) > 0 && strings.EqualFold(parameters[len(parameters)-1], texts.Buy.FirstBuyFlag) {
+		firstBuy = true
+		parameters = parameters[:len(parameters)-1]
+	}
+
 	if len(parameters) != 1 {
 		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.IslandPrice.Params))
 		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
@@ -263,7 +495,7 @@ func (t *Telegram) handleIslandPriceCmd(ctx tb.Context) error {
 	}
 
 	// Store island price
-	newIP, oldIslandPrice, err := db.SaveUserIslandPrice(m.Sender, m.Chat, islandPrice)
+	newIP, oldIslandPrice, err := db.SaveUserIslandPrice(m.Sender, m.Chat, islandPrice, firstBuy)
 	if err != nil {
 		rm := t.reply(m, texts.InternalError)
 		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
@@ -277,17 +509,30 @@ func (t *Telegram) handleIslandPriceCmd(ctx tb.Context) error {
 		msgTxt = fmt.Sprintf(texts.IslandPrice.Changed, islandPrice, oldIslandPrice)
 	}
 
+	if firstBuy {
+		msgTxt += "\n\n" + texts.IslandPrice.FirstBuy
+	}
+
 	rm := t.reply(m, msgTxt)
 	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 
 	return nil
 }
 
-// handleSellCmd triggers when the sell cmd is sent to a group
+// handleSellCmd triggers when the sell cmd is sent to a group, or to a private dashboard
 func (t *Telegram) handleSellCmd(ctx tb.Context) error {
 	m := ctx.Message()
-	if m.Private() {
-		t.send(m.Chat, texts.GroupOnly)
+	texts := t.texts(m.Chat, m.Sender)
+
+	// In private chats use the dashboard selected group
+	chat, err := t.groupChat(m)
+	if err != nil {
+		t.reply(m, texts.InternalError)
+		return nil
+	}
+
+	if chat == nil {
+		t.reply(m, fmt.Sprintf(texts.Dashboard.NoGroup, texts.Dashboard.Cmd))
 		return nil
 	}
 
@@ -298,8 +543,14 @@ func (t *Telegram) handleSellCmd(ctx tb.Context) error {
 		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
 		Msg(m.Text)
 
-	// Validate the parameters
+	// Without parameters ask for the half-day using a keyboard, messages are cleaned up once the price is answered
 	parameters := strings.Fields(m.Payload)
+	if len(parameters) == 0 {
+		t.sendSellKeyboard(texts, m, chat)
+		return nil
+	}
+
+	// Validate the parameters
 	if len(parameters) != 1 && len(parameters) != 3 {
 		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Sell.Params))
 		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
@@ -313,44 +564,44 @@ func (t *Telegram) handleSellCmd(ctx tb.Context) error {
 		return nil
 	}
 
+	dateStr := strings.Join(parameters[1:], " ")
+
+	// Prices that do not fit the forecast need a confirmation, messages are cleaned up once it is answered
+	if t.askSellConfirmation(texts, m, chat, bells, dateStr) {
+		return nil
+	}
+
 	// Save the price
+	rm := t.saveSellPrice(texts, m, chat, bells, dateStr, dateStr == "")
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+
+	return nil
+}
+
+// saveSellPrice saves the price of the message sender for a date, or the current one if empty, and replies the result.
+// Current prices trigger the alerts.
+func (t *Telegram) saveSellPrice(texts *Texts, m *tb.Message, chat *tb.Chat, bells uint32, dateStr string, current bool) *tb.Message {
 	var (
 		new      bool
 		oldBells uint32
 		date     string
+		err      error
 	)
 
-	if len(parameters) == 1 {
-		new, oldBells, date, err = db.SaveUserCurrentPrice(m.Sender, m.Chat, bells)
-		if err != nil {
-			if err == ErrBuyDay {
-				rm := t.reply(m, fmt.Sprintf(texts.Sell.NoMarketToday, date, texts.Days[turnipSellDay]))
-				t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
-				return nil
-			}
-
-			rm := t.reply(m, texts.InternalError)
-			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
-			return nil
-		}
+	if dateStr == "" {
+		new, oldBells, date, err = db.SaveUserCurrentPrice(m.Sender, chat, bells)
 	} else {
-		new, oldBells, date, err = db.SaveUserPrice(m.Sender, m.Chat, bells, strings.Join(parameters[1:], " "))
-		if err != nil {
-			if err == ErrDateParse {
-				rm := t.reply(m, fmt.Sprintf(texts.Sell.InvalidDate, strings.Join(parameters[1:], " ")))
-				t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
-				return nil
-			}
-
-			if err == ErrBuyDay {
-				rm := t.reply(m, fmt.Sprintf(texts.Sell.NoMarketToday, date, texts.Days[turnipSellDay]))
-				t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
-				return nil
-			}
+		new, oldBells, date, err = db.SaveUserPrice(m.Sender, chat, bells, dateStr)
+	}
 
-			rm := t.reply(m, texts.InternalError)
-			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
-			return nil
+	if err != nil {
+		switch err {
+		case ErrDateParse:
+			return t.reply(m, fmt.Sprintf(texts.Sell.InvalidDate, dateStr))
+		case ErrBuyDay:
+			return t.reply(m, fmt.Sprintf(texts.Sell.NoMarketToday, date, texts.Days[turnipSellDay]))
+		default:
+			return t.reply(m, texts.InternalError)
 		}
 	}
 
@@ -360,277 +611,2222 @@ func (t *Telegram) handleSellCmd(ctx tb.Context) error {
 	} else {
 		rm = t.reply(m, fmt.Sprintf(texts.Sell.Changed, bells, date, oldBells))
 	}
-	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 
-	return nil
-}
-
-// handleListCmd triggers when the list cmd is sent to a group
-func (t *Telegram) handleListCmd(ctx tb.Context) error {
-	m := ctx.Message()
-	if m.Private() {
-		t.send(m.Chat, texts.GroupOnly)
-		return nil
+	// Only current prices are worth an alert
+	if current {
+		t.spikeAlert(chat, m.Sender, bells, date)
 	}
 
-	log.Info().
-		Str("module", "telegram").
-		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
-		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
-		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
-		Msg(m.Text)
+	return rm
+}
 
-	owned, err := db.GetUserWeekOwned(m.Sender, m.Chat)
+// sendSellKeyboard replies with a keyboard of this week half-days until now, marking the ones with a saved price
+func (t *Telegram) sendSellKeyboard(texts *Texts, m *tb.Message, chat *tb.Chat) *tb.Message {
+	user, group, err := db.GetUserAndGroup(m.Sender, chat)
 	if err != nil {
-		rm := t.reply(m, texts.InternalError)
-		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
-		return nil
+		return t.reply(m, texts.InternalError)
 	}
 
-	cost := int64(owned.Units * owned.Bells)
+	now := time.Now()
 
-	prices, date, err := db.GetGroupCurrentPrices(m.Chat)
+	week, err := newUserWeekPrices(user, group, now)
 	if err != nil {
-		rm := t.reply(m, texts.InternalError)
-		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
-		return nil
+		return t.reply(m, texts.InternalError)
 	}
 
-	var reply string
-
-	if cost > 0 {
-		mention := fmt.Sprintf("<a href=\"tg://user?id=%v\">%s</a>", m.Sender.ID, m.Sender.FirstName)
-
-		if len(m.Sender.Username) > 0 {
-			mention = "@" + m.Sender.Username
-		}
+	halfDay := week.HalfDay(now)
+	if halfDay < 0 {
+		return t.reply(m, texts.Sell.NoHalfDays)
+	}
 
-		reply += fmt.Sprintf(texts.List.Owned, mention, owned.Units, owned.Bells) + "\n\n"
+	if halfDay >= len(week.Times) {
+		halfDay = len(week.Times) - 1
 	}
 
-	if len(prices) == 0 {
-		reply += fmt.Sprintf(texts.List.NoPrices, date)
-	} else {
-		reply += fmt.Sprintf(texts.List.Prices, date) + "\n"
+	// One row per day with its AM and PM buttons
+	markup := &tb.ReplyMarkup{}
+	rows := []tb.Row{}
 
-		for _, price := range prices {
-			reply += "\n<code>"
+	for day := 0; day <= halfDay; day += 2 {
+		btns := []tb.Btn{}
 
-			if price.User.Username != "" {
-				reply += fmt.Sprintf("@%s", price.User.Username)
-			} else {
-				reply += price.User.Name()
+		for i := day; i < day+2 && i <= halfDay; i++ {
+			label := TimeToShortDayAMPM(texts, week.Times[i])
+			if week.Prices[i] > 0 {
+				label += fmt.Sprintf(texts.Sell.Filled, week.Prices[i])
 			}
 
-			reply += fmt.Sprintf("</code>: <b>%v</b> %s", price.Bells, texts.Bells)
-
-			if cost > 0 {
-				var profits int64 = int64(owned.Units*price.Bells) - cost
-
-				if profits > 0 {
-					reply += " 📈 "
-				} else {
-					reply += " 📉 "
-				}
-
-				reply += fmt.Sprintf("<b>%v</b>", profits)
-			}
+			btns = append(btns, markup.Data(label, sellHalfDayBtn, strconv.FormatInt(m.Sender.ID, 10), week.Times[i].Format(timeFormatAMPM)))
 		}
+
+		rows = append(rows, markup.Row(btns...))
 	}
 
-	t.send(m.Chat, reply)
-	t.cleanupChatMsgs(m.Chat, []*tb.Message{m})
+	markup.Inline(rows...)
 
-	return nil
+	return t.reply(m, texts.Sell.Keyboard, markup)
 }
 
-// handleChartCmd triggers when the chart cmd is sent to a group
-func (t *Telegram) handleChartCmd(ctx tb.Context) error {
-	m := ctx.Message()
-	if m.Private() {
-		t.send(m.Chat, texts.GroupOnly)
-		return nil
-	}
+// handleSellHalfDayBtn triggers when a half-day of the sell keyboard is pressed
+func (t *Telegram) handleSellHalfDayBtn(ctx tb.Context) error {
+	cb := ctx.Callback()
+	texts := t.texts(cb.Message.Chat, cb.Sender)
 
 	log.Info().
 		Str("module", "telegram").
-		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
-		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
-		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
-		Msg(m.Text)
+		Int64("chat_id", cb.Message.Chat.ID).Str("chat_title", cb.Message.Chat.Title).
+		Int64("user_id", cb.Sender.ID).Str("user_first_name", cb.Sender.FirstName).
+		Str("user_last_name", cb.Sender.LastName).Str("user_username", cb.Sender.Username).
+		Msg(cb.Data)
+
+	// Data is the keyboard owner and the half-day date
+	data := strings.Split(cb.Data, "|")
+	if len(data) != 2 {
+		return ctx.Respond()
+	}
 
-	// Get group timezone
-	user, group, err := db.GetUserAndGroup(m.Sender, m.Chat)
+	userID, err := parseInt64(data[0])
 	if err != nil {
-		rm := t.reply(m, texts.InternalError)
-		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
-		return nil
+		return ctx.Respond()
 	}
 
-	groupNow, err := group.NowConfig()
-	if err != nil {
-		rm := t.reply(m, texts.InternalError)
-		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
-		return nil
+	if userID != cb.Sender.ID {
+		return ctx.Respond(&tb.CallbackResponse{Text: texts.Sell.NotYours, ShowAlert: true})
 	}
 
-	// Get prices
-	prices, err := db.GetUserWeekPrices(m.Sender, m.Chat, time.Now())
+	err = ctx.Respond()
 	if err != nil {
-		rm := t.reply(m, texts.InternalError)
-		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		log.Error().Str("module", "telegram").Err(err).Msg("error responding callback")
+	}
+
+	// In private chats use the dashboard selected group, the answer is saved there even if the selection changes
+	chat, err := t.groupChat(&tb.Message{Chat: cb.Message.Chat, Sender: cb.Sender})
+	if err != nil || chat == nil {
 		return nil
 	}
 
-	if len(prices) == 0 {
-		rm := t.reply(m, texts.Chart.NoPrices)
-		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+	group, err := db.GetGroup(chat)
+	if err != nil {
 		return nil
 	}
 
-	// Get owned
-	owned, err := db.GetUserWeekOwned(m.Sender, m.Chat)
+	nowCfg, err := group.NowConfig()
 	if err != nil {
-		rm := t.reply(m, texts.InternalError)
-		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 		return nil
 	}
 
-	// Get island price
-	islandPrice, err := db.GetUserIslandPrice(m.Sender, m.Chat)
+	date, err := parseHalfDay(data[1], nowCfg, time.Now())
 	if err != nil {
-		rm := t.reply(m, texts.InternalError)
-		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 		return nil
 	}
 
-	// Craft data to have a good looking graph when data is missing
-	times := [12]time.Time{}
-	buyPrices := [12]uint32{}
+	// Replace the keyboard with a question replying to the sell command, so the answer is sent to us even in groups
+	err = t.bot.Delete(cb.Message)
+	if err != nil {
+		log.Error().Str("module", "telegram").Err(err).Msg("failed deleting message")
+	}
 
-	initDate := groupNow.With(time.Now().In(groupNow.TimeLocation)).BeginningOfWeek().Add(time.Hour * 24)
+	question := fmt.Sprintf(texts.Sell.AskBells, data[1])
+	markup := &tb.ReplyMarkup{ForceReply: true, Selective: true}
 
-	for i := 0; i < 12; i++ {
-		times[i] = initDate.Add(time.Hour * 12 * time.Duration(i))
+	var pm *tb.Message
+	if cb.Message.ReplyTo != nil {
+		pm = t.reply(cb.Message.ReplyTo, question, markup)
+	} else {
+		pm = t.send(cb.Message.Chat, question, markup)
 	}
 
-	for _, price := range prices {
-		for i := range times {
-			if price.Date.Equal(times[i]) {
-				buyPrices[i] = price.Bells
-				break
+	if pm == nil {
+		return nil
+	}
+
+	t.setPricePrompt(cb.Message.Chat, cb.Sender, &pricePrompt{
+		chat:    chat,
+		date:    data[1],
+		expires: nowCfg.With(date).BeginningOfWeek().AddDate(0, 0, 7),
+		msg:     pm,
+	})
+
+	return nil
+}
+
+// askSellConfirmation asks the message sender to confirm a price if it does not fit any pattern of their forecast,
+// returns if the confirmation was asked
+func (t *Telegram) askSellConfirmation(texts *Texts, m *tb.Message, chat *tb.Chat, bells uint32, dateStr string) bool {
+	user, group, err := db.GetUserAndGroup(m.Sender, chat)
+	if err != nil {
+		return false
+	}
+
+	nowCfg, err := group.NowConfig()
+	if err != nil {
+		return false
+	}
+
+	now := time.Now()
+	date := currentHalfDay(nowCfg, now)
+
+	// Invalid dates are reported when saving the price
+	if dateStr != "" {
+		date, err = parseHalfDay(dateStr, nowCfg, now)
+		if err != nil {
+			return false
+		}
+	}
+
+	week, err := NewUserWeek(user, group, date)
+	if err != nil {
+		return false
+	}
+
+	possible, err := week.PossiblePrice(date, bells)
+	if err != nil || possible {
+		return false
+	}
+
+	// Buttons carry the owner, the price, the date and if it was the current price so it still triggers alerts
+	markup := &tb.ReplyMarkup{}
+	owner := strconv.FormatInt(m.Sender.ID, 10)
+	current := strconv.FormatBool(dateStr == "")
+
+	markup.Inline(markup.Row(
+		markup.Data(texts.Sell.Confirm, sellConfirmBtn, owner, strconv.FormatUint(uint64(bells), 10), date.Format(timeFormatAMPM), current),
+		markup.Data(texts.Sell.Cancel, sellCancelBtn, owner),
+	))
+
+	t.reply(m, fmt.Sprintf(texts.Sell.Outlier, bells, date.Format(timeFormatAMPM)), markup)
+
+	return true
+}
+
+// sellCallbackOwner checks the sender of a sell confirmation callback is the one who sent the price
+func (t *Telegram) sellCallbackOwner(texts *Texts, ctx tb.Context, owner string) bool {
+	cb := ctx.Callback()
+
+	userID, err := parseInt64(owner)
+	if err != nil {
+		if err = ctx.Respond(); err != nil {
+			log.Error().Str("module", "telegram").Err(err).Msg("error responding callback")
+		}
+
+		return false
+	}
+
+	if userID != cb.Sender.ID {
+		if err = ctx.Respond(&tb.CallbackResponse{Text: texts.Sell.NotYours, ShowAlert: true}); err != nil {
+			log.Error().Str("module", "telegram").Err(err).Msg("error responding callback")
+		}
+
+		return false
+	}
+
+	if err = ctx.Respond(); err != nil {
+		log.Error().Str("module", "telegram").Err(err).Msg("error responding callback")
+	}
+
+	return true
+}
+
+// handleSellConfirmBtn triggers when a price that does not fit the forecast is confirmed
+func (t *Telegram) handleSellConfirmBtn(ctx tb.Context) error {
+	cb := ctx.Callback()
+	texts := t.texts(cb.Message.Chat, cb.Sender)
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", cb.Message.Chat.ID).Str("chat_title", cb.Message.Chat.Title).
+		Int64("user_id", cb.Sender.ID).Str("user_first_name", cb.Sender.FirstName).
+		Str("user_last_name", cb.Sender.LastName).Str("user_username", cb.Sender.Username).
+		Msg(cb.Data)
+
+	// Data is the price owner, the price, the date and if it was the current price
+	data := strings.Split(cb.Data, "|")
+	if len(data) != 4 {
+		return ctx.Respond()
+	}
+
+	if !t.sellCallbackOwner(texts, ctx, data[0]) {
+		return nil
+	}
+
+	bells, err := parseUint32(data[1])
+	if err != nil || bells > 660 {
+		return nil
+	}
+
+	current, err := strconv.ParseBool(data[3])
+	if err != nil {
+		return nil
+	}
+
+	// The price message is the one the confirmation replies to
+	m := cb.Message.ReplyTo
+	if m == nil {
+		m = &tb.Message{Chat: cb.Message.Chat, Sender: cb.Sender}
+	}
+
+	// In private chats use the dashboard selected group
+	chat, err := t.groupChat(&tb.Message{Chat: cb.Message.Chat, Sender: cb.Sender})
+	if err != nil {
+		t.reply(m, texts.InternalError)
+		return nil
+	}
+
+	if chat == nil {
+		t.reply(m, fmt.Sprintf(texts.Dashboard.NoGroup, texts.Dashboard.Cmd))
+		return nil
+	}
+
+	err = t.bot.Delete(cb.Message)
+	if err != nil {
+		log.Error().Str("module", "telegram").Err(err).Msg("failed deleting message")
+	}
+
+	rm := t.saveSellPrice(texts, m, chat, bells, data[2], current)
+	t.cleanupChatMsgs(cb.Message.Chat, []*tb.Message{m, rm})
+
+	return nil
+}
+
+// handleSellCancelBtn triggers when a price that does not fit the forecast is discarded
+func (t *Telegram) handleSellCancelBtn(ctx tb.Context) error {
+	cb := ctx.Callback()
+	texts := t.texts(cb.Message.Chat, cb.Sender)
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", cb.Message.Chat.ID).Str("chat_title", cb.Message.Chat.Title).
+		Int64("user_id", cb.Sender.ID).Str("user_first_name", cb.Sender.FirstName).
+		Str("user_last_name", cb.Sender.LastName).Str("user_username", cb.Sender.Username).
+		Msg(cb.Data)
+
+	if !t.sellCallbackOwner(texts, ctx, cb.Data) {
+		return nil
+	}
+
+	_, err := t.bot.Edit(cb.Message, texts.Sell.Cancelled)
+	if err != nil {
+		log.Error().Str("module", "telegram").Err(err).Msg("failed editing message")
+	}
+
+	msgs := []*tb.Message{cb.Message}
+	if cb.Message.ReplyTo != nil {
+		msgs = append(msgs, cb.Message.ReplyTo)
+	}
+
+	t.cleanupChatMsgs(cb.Message.Chat, msgs)
+
+	return nil
+}
+
+// handleText triggers when a text that isn't a command is received, it is only used for answers to the bot questions
+func (t *Telegram) handleText(ctx tb.Context) error {
+	m := ctx.Message()
+
+	prompt, ok := t.pricePrompt(m)
+	if !ok {
+		return nil
+	}
+
+	texts := t.texts(m.Chat, m.Sender)
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	chat := prompt.chat
+
+	// Keep asking in groups until a valid price is answered, in private chats any message answers so give up
+	bells, err := parseUint32(strings.TrimSpace(m.Text))
+	if err != nil || bells > 660 {
+		if m.Private() {
+			t.clearPricePrompt(m)
+			t.reply(m, fmt.Sprintf(texts.Sell.Unanswered, texts.Sell.Cmd))
+			return nil
+		}
+
+		rm := t.reply(m, texts.Sell.InvalidBells)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	t.clearPricePrompt(m)
+
+	msgs := []*tb.Message{prompt.msg}
+	if prompt.msg.ReplyTo != nil {
+		msgs = append(msgs, prompt.msg.ReplyTo)
+	}
+
+	// Prices that do not fit the forecast need a confirmation, the answer is cleaned up once it is confirmed
+	if t.askSellConfirmation(texts, m, chat, bells, prompt.date) {
+		t.cleanupChatMsgs(m.Chat, msgs)
+		return nil
+	}
+
+	rm := t.saveSellPrice(texts, m, chat, bells, prompt.date, prompt.date == "")
+	t.cleanupChatMsgs(m.Chat, append(msgs, m, rm))
+
+	return nil
+}
+
+// handleWeekCmd triggers when the week cmd is sent to a group, or to a private dashboard
+func (t *Telegram) handleWeekCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	// In private chats use the dashboard selected group
+	chat, err := t.groupChat(m)
+	if err != nil {
+		t.reply(m, texts.InternalError)
+		return nil
+	}
+
+	if chat == nil {
+		t.reply(m, fmt.Sprintf(texts.Dashboard.NoGroup, texts.Dashboard.Cmd))
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	// Validate the parameters
+	parameters := strings.Fields(m.Payload)
+	if len(parameters) == 0 || len(parameters) > 12 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Week.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	prices := map[int]uint32{}
+
+	for halfDay, parameter := range parameters {
+		if parameter == "-" {
+			continue
+		}
+
+		bells, errp := parseUint32(parameter)
+		if errp != nil || bells > 660 {
+			rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Week.Params))
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+		prices[halfDay] = bells
+	}
+
+	if len(prices) == 0 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Week.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Save the prices
+	err = db.SaveUserWeekPrices(m.Sender, chat, prices)
+	if err != nil {
+		var rm *tb.Message
+
+		var futureErr *FutureHalfDayError
+		if errors.As(err, &futureErr) {
+			rm = t.reply(m, fmt.Sprintf(texts.Week.Future, futureErr.Date.Format(timeFormatAMPM)))
+		} else {
+			rm = t.reply(m, texts.InternalError)
+		}
+
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Reply with the refreshed forecast
+	user, group, err := db.GetUserAndGroup(m.Sender, chat)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	week, err := NewUserWeek(user, group, time.Now())
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	rm := t.reply(m, fmt.Sprintf(texts.Week.Saved, len(prices))+"\n\n"+weekPricesText(texts, week)+"\n\n"+weekPatternsText(texts, week))
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+
+	return nil
+}
+
+// handleSoldCmd triggers when the sold cmd is sent to a group
+func (t *Telegram) handleSoldCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	if m.Private() {
+		t.send(m.Chat, texts.GroupOnly)
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	// Validate the parameters
+	parameters := strings.Fields(m.Payload)
+	if len(parameters) != 2 && len(parameters) != 4 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Sold.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	units, err := parseUint32(parameters[0])
+	bells, err2 := parseUint32(parameters[1])
+	if err != nil || err2 != nil || units == 0 || bells > 660 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Sold.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	if math.Mod(float64(units), 10) != 0 {
+		rm := t.reply(m, texts.Buy.UnitsModTen)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Save the sale
+	dateStr := strings.Join(parameters[2:], " ")
+
+	owned, date, err := db.SaveThisWeekSale(m.Sender, m.Chat, units, bells, dateStr)
+	if err != nil {
+		var rm *tb.Message
+
+		switch err {
+		case ErrDateParse:
+			rm = t.reply(m, fmt.Sprintf(texts.Sell.InvalidDate, dateStr))
+		case ErrBuyDay:
+			rm = t.reply(m, fmt.Sprintf(texts.Sell.NoMarketToday, date, texts.Days[turnipSellDay]))
+		case ErrNotThisWeek:
+			rm = t.reply(m, fmt.Sprintf(texts.Sold.NotThisWeek, date))
+		case ErrNoOwned:
+			rm = t.reply(m, fmt.Sprintf(texts.Sold.NoOwned, texts.Buy.Cmd))
+		case ErrNotEnoughUnits:
+			rm = t.reply(m, fmt.Sprintf(texts.Sold.NotEnoughUnits, owned.RemainingUnits()))
+		default:
+			rm = t.reply(m, texts.InternalError)
+		}
+
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	profits := int64(units)*int64(bells) - int64(units)*int64(owned.Bells)
+
+	rm := t.reply(m, fmt.Sprintf(texts.Sold.Saved, units, bells, date, profits, owned.RemainingUnits(), owned.RealizedProfits()))
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+
+	return nil
+}
+
+// spikeAlert alerts the members with turnips if the price is high enough for the group alert settings
+func (t *Telegram) spikeAlert(chat *tb.Chat, u *tb.User, bells uint32, date string) {
+	group, err := db.GetGroup(chat)
+	if err != nil || (group.AlertBells == 0 && group.AlertMargin == 0) {
+		return
+	}
+
+	owneds, err := db.GetGroupWeekOwned(chat)
+	if err != nil {
+		return
+	}
+
+	// The alert is for the whole group
+	texts := langTexts(group.Lang)
+
+	// Check if the price triggers the alert and craft the owners list
+	beatsAll := group.AlertMargin > 0
+	owners := ""
+
+	for _, owned := range owneds {
+		if owned.RemainingUnits() == 0 || owned.UserID == u.ID {
+			continue
+		}
+
+		if uint64(bells)*100 < uint64(owned.Bells)*(100+uint64(group.AlertMargin)) {
+			beatsAll = false
+		}
+
+		if owned.User.NoAlerts {
+			continue
+		}
+
+		units := owned.RemainingUnits()
+		profits := int64(units)*int64(bells) - int64(units)*int64(owned.Bells)
+		owners += fmt.Sprintf(texts.SpikeAlerts.Owner, owned.User.Mention(), units, profits)
+	}
+
+	if owners == "" || !((group.AlertBells > 0 && bells >= group.AlertBells) || beatsAll) {
+		return
+	}
+
+	sender, err := db.GetUser(u)
+	if err != nil {
+		return
+	}
+
+	alert := fmt.Sprintf(texts.SpikeAlerts.Alert, sender.Mention(), bells, texts.Bells, date)
+	alert += owners
+	alert += fmt.Sprintf(texts.SpikeAlerts.OptOut, texts.SpikeAlerts.Cmd, texts.Off)
+
+	t.send(chat, alert)
+}
+
+// handleUndoCmd triggers when the undo cmd is sent to a group, or to a private dashboard
+func (t *Telegram) handleUndoCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	// In private chats use the dashboard selected group
+	chat, err := t.groupChat(m)
+	if err != nil {
+		t.reply(m, texts.InternalError)
+		return nil
+	}
+
+	if chat == nil {
+		t.reply(m, fmt.Sprintf(texts.Dashboard.NoGroup, texts.Dashboard.Cmd))
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	group, err := db.GetGroup(chat)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	nowCfg, err := group.NowConfig()
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	entries, err := db.UndoUserLastChange(m.Sender, chat)
+	if err != nil {
+		var rm *tb.Message
+
+		switch err {
+		case ErrNoChanges:
+			rm = t.reply(m, texts.Undo.NoChanges)
+		case ErrChangedSince:
+			rm = t.reply(m, texts.Undo.ChangedSince)
+		case ErrUnauditedSince:
+			rm = t.reply(m, texts.Undo.UnauditedSince)
+		case ErrHasSales:
+			rm = t.reply(m, texts.Clear.HasSales)
+		default:
+			rm = t.reply(m, texts.InternalError)
+		}
+
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Show the reverted changes
+	reply := texts.Undo.Done
+
+	for _, entry := range entries {
+		reply += "\n- " + auditEntryText(texts, entry, nowCfg.TimeLocation)
+	}
+
+	rm := t.reply(m, reply)
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+
+	return nil
+}
+
+// handleClearCmd triggers when the clear cmd is sent to a group, or to a private dashboard
+func (t *Telegram) handleClearCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	// In private chats use the dashboard selected group
+	chat, err := t.groupChat(m)
+	if err != nil {
+		t.reply(m, texts.InternalError)
+		return nil
+	}
+
+	if chat == nil {
+		t.reply(m, fmt.Sprintf(texts.Dashboard.NoGroup, texts.Dashboard.Cmd))
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	// Validate the parameters
+	parameters := strings.Fields(m.Payload)
+	if len(parameters) == 0 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Clear.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	record := AuditPrice
+	dateStr := strings.Join(parameters, " ")
+
+	if len(parameters) == 1 && strings.EqualFold(parameters[0], texts.Clear.Buy) {
+		record = AuditOwned
+	} else if len(parameters) == 1 && strings.EqualFold(parameters[0], texts.Clear.Island) {
+		record = AuditIslandPrice
+	}
+
+	// Remove the record
+	date, err := db.ClearUserRecord(m.Sender, chat, record, dateStr)
+	if err != nil {
+		var rm *tb.Message
+
+		switch err {
+		case ErrDateParse:
+			rm = t.reply(m, fmt.Sprintf(texts.Sell.InvalidDate, dateStr))
+		case ErrNoRecord:
+			rm = t.reply(m, texts.Clear.NoRecord)
+		case ErrHasSales:
+			rm = t.reply(m, texts.Clear.HasSales)
+		default:
+			rm = t.reply(m, texts.InternalError)
+		}
+
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	var rm *tb.Message
+
+	switch record {
+	case AuditPrice:
+		rm = t.reply(m, fmt.Sprintf(texts.Clear.PriceCleared, date))
+	case AuditOwned:
+		rm = t.reply(m, texts.Clear.OwnedCleared)
+	case AuditIslandPrice:
+		rm = t.reply(m, texts.Clear.IslandPriceCleared)
+	}
+
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+
+	return nil
+}
+
+// handleImportCmd triggers when the import cmd is sent to a group, or to a private dashboard
+func (t *Telegram) handleImportCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	// In private chats use the dashboard selected group
+	chat, err := t.groupChat(m)
+	if err != nil {
+		t.reply(m, texts.InternalError)
+		return nil
+	}
+
+	if chat == nil {
+		t.reply(m, fmt.Sprintf(texts.Dashboard.NoGroup, texts.Dashboard.Cmd))
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	group, err := db.GetGroup(chat)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	nowCfg, err := group.NowConfig()
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Validate the parameters, a CSV document is imported replying to it
+	parameters := strings.Fields(m.Payload)
+
+	var data *UserImport
+
+	switch {
+	case len(parameters) == 0 && m.ReplyTo != nil && m.ReplyTo.Document != nil:
+		if m.ReplyTo.Sender == nil || m.ReplyTo.Sender.ID != m.Sender.ID {
+			rm := t.reply(m, texts.Import.NotYours)
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+		if m.ReplyTo.Document.FileSize > importMaxSize {
+			rm := t.reply(m, texts.Import.TooBig)
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+		file, errf := t.bot.File(&m.ReplyTo.Document.File)
+		if errf != nil {
+			log.Error().Str("module", "telegram").Err(errf).Msg("error downloading import")
+			rm := t.reply(m, texts.InternalError)
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+		data, err = parseImportCSV(io.LimitReader(file, importMaxSize), nowCfg, m.Sender.ID)
+		file.Close()
+
+		if err != nil {
+			var rm *tb.Message
+
+			var rowErr *ImportRowError
+			if errors.As(err, &rowErr) {
+				rm = t.reply(m, fmt.Sprintf(texts.Import.InvalidRow, rowErr.Row))
+			} else {
+				rm = t.reply(m, texts.Import.InvalidCSV)
+			}
+
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+	case len(parameters) == 1 || len(parameters) == 2:
+		week := time.Now()
+
+		if len(parameters) == 2 {
+			week, err = time.ParseInLocation("2006-01-02", parameters[1], nowCfg.TimeLocation)
+			if err != nil {
+				rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Import.Params))
+				t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+				return nil
+			}
+		}
+
+		data, err = parseTurnipProphetQuery(parameters[0], nowCfg, week)
+		if err != nil {
+			var rm *tb.Message
+
+			var futureErr *FutureHalfDayError
+			if errors.As(err, &futureErr) {
+				rm = t.reply(m, fmt.Sprintf(texts.Week.Future, futureErr.Date.Format(timeFormatAMPM)))
+			} else {
+				rm = t.reply(m, texts.Import.InvalidQuery)
+			}
+
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+	default:
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Import.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Save everything or nothing
+	changed, err := db.ImportUserData(m.Sender, chat, data)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	reply := fmt.Sprintf(texts.Import.Done, changed, len(data.Records)-changed, len(data.KnownPatterns))
+	if changed > 0 {
+		reply += " " + fmt.Sprintf(texts.Import.Revert, texts.Undo.Cmd)
+
+		// Known patterns are not audited
+		if len(data.KnownPatterns) > 0 {
+			reply += " " + fmt.Sprintf(texts.Import.PatternsKept, texts.LastPattern.Cmd)
+		}
+	}
+
+	rm := t.reply(m, reply)
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+
+	return nil
+}
+
+// handleLastPatternCmd triggers when the last pattern cmd is sent to a group
+func (t *Telegram) handleLastPatternCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	if m.Private() {
+		t.send(m.Chat, texts.GroupOnly)
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	// Validate the parameters
+	parameters := strings.Fields(m.Payload)
+	if len(parameters) != 1 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, fmt.Sprintf(texts.LastPattern.Params, patternKeys(texts))))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	pattern, ok := texts.PatternByKey(parameters[0])
+	if !ok {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, fmt.Sprintf(texts.LastPattern.Params, patternKeys(texts))))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Store last week pattern
+	new, oldPattern, err := db.SaveUserLastWeekPattern(m.Sender, m.Chat, pattern)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	pName, _ := texts.PatternText(pattern)
+
+	var rm *tb.Message
+	if new || oldPattern == pattern {
+		rm = t.reply(m, fmt.Sprintf(texts.LastPattern.Saved, pName))
+	} else {
+		oldPName, _ := texts.PatternText(oldPattern)
+		rm = t.reply(m, fmt.Sprintf(texts.LastPattern.Changed, pName, oldPName))
+	}
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+
+	return nil
+}
+
+// handleListCmd triggers when the list cmd is sent to a group, or to a private dashboard
+func (t *Telegram) handleListCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	// In private chats use the dashboard selected group
+	chat, err := t.groupChat(m)
+	if err != nil {
+		t.reply(m, texts.InternalError)
+		return nil
+	}
+
+	if chat == nil {
+		t.reply(m, fmt.Sprintf(texts.Dashboard.NoGroup, texts.Dashboard.Cmd))
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	owned, err := db.GetUserWeekOwned(m.Sender, chat)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	units := owned.RemainingUnits()
+	cost := int64(units) * int64(owned.Bells)
+
+	prices, date, err := db.GetGroupCurrentPrices(chat)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	var reply string
+
+	if cost > 0 {
+		mention := fmt.Sprintf("<a href=\"tg://user?id=%v\">%s</a>", m.Sender.ID, m.Sender.FirstName)
+
+		if len(m.Sender.Username) > 0 {
+			mention = "@" + m.Sender.Username
+		}
+
+		reply += fmt.Sprintf(texts.List.Owned, mention, owned.Units, owned.Bells) + "\n\n"
+	}
+
+	if len(prices) == 0 {
+		reply += fmt.Sprintf(texts.List.NoPrices, date)
+	} else {
+		reply += fmt.Sprintf(texts.List.Prices, date) + "\n"
+
+		for _, price := range prices {
+			reply += "\n<code>"
+
+			if price.User.Username != "" {
+				reply += fmt.Sprintf("@%s", price.User.Username)
+			} else {
+				reply += price.User.Name()
+			}
+
+			reply += fmt.Sprintf("</code>: <b>%v</b> %s", price.Bells, texts.Bells)
+
+			if cost > 0 {
+				var profits int64 = int64(units)*int64(price.Bells) - cost
+
+				if profits > 0 {
+					reply += " 📈 "
+				} else {
+					reply += " 📉 "
+				}
+
+				reply += fmt.Sprintf("<b>%v</b>", profits)
+			}
+		}
+	}
+
+	t.send(m.Chat, reply)
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m})
+
+	return nil
+}
+
+// handleChartCmd triggers when the chart cmd is sent to a group, or to a private dashboard
+func (t *Telegram) handleChartCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	// In private chats use the dashboard selected group
+	chat, err := t.groupChat(m)
+	if err != nil {
+		t.reply(m, texts.InternalError)
+		return nil
+	}
+
+	if chat == nil {
+		t.reply(m, fmt.Sprintf(texts.Dashboard.NoGroup, texts.Dashboard.Cmd))
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	// Get group timezone
+	user, group, err := db.GetUserAndGroup(m.Sender, chat)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	groupNow, err := group.NowConfig()
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Get prices and forecast
+	week, err := NewUserWeek(user, group, time.Now())
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	if !week.HasPrices() {
+		rm := t.reply(m, texts.Chart.NoPrices)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Get owned
+	owned, err := db.GetUserWeekOwned(m.Sender, chat)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Generate chart
+	chart, err := PricesChart(texts, user.String(), &week.Times, &week.Prices, owned.Bells, week.Forecast, groupNow.TimeLocation)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Add pattern info and a link to cross-check it as image caption
+	caption := weekPatternsText(texts, week) + "\n\n" + fmt.Sprintf(texts.Chart.TurnipProphet, html.EscapeString(week.TurnipProphetURL()))
+
+	t.send(m.Chat, &tb.Photo{File: tb.FromReader(chart), Caption: caption})
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m})
+
+	return nil
+}
+
+// handleAdviceCmd triggers when the advice cmd is sent to a group
+func (t *Telegram) handleAdviceCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	if m.Private() {
+		t.send(m.Chat, texts.GroupOnly)
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	// Get owned
+	owned, err := db.GetUserWeekOwned(m.Sender, m.Chat)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	units := owned.RemainingUnits()
+	if units == 0 {
+		rm := t.reply(m, texts.Advice.NoOwned)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Get prices and forecast
+	user, group, err := db.GetUserAndGroup(m.Sender, m.Chat)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	now := time.Now()
+
+	week, err := NewUserWeek(user, group, now)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	if !week.HasIslandPrice() {
+		rm := t.reply(m, texts.Patterns.NoIslandPrice)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	if len(week.Forecast.Patterns) == 0 {
+		rm := t.reply(m, texts.Patterns.Unknown)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Compare selling now against the rest of the week
+	halfDay := week.HalfDay(now)
+
+	var currentPrice uint32 = 0
+	if halfDay >= 0 && halfDay < len(week.Prices) {
+		currentPrice = week.Prices[halfDay]
+	}
+
+	lines := []string{}
+
+	if currentPrice > 0 {
+		profits := int64(units)*int64(currentPrice) - int64(units)*int64(owned.Bells)
+		lines = append(lines, fmt.Sprintf(texts.Advice.Now, currentPrice, profits))
+	} else if halfDay >= 0 && halfDay < len(week.Prices) {
+		lines = append(lines, fmt.Sprintf(texts.Advice.NoCurrentPrice, texts.Sell.Cmd))
+	}
+
+	if halfDay+1 >= len(week.Prices) {
+		lines = append(lines, texts.Advice.LastChance)
+	} else {
+		expected, chance := week.Forecast.MaxFrom(halfDay+1, owned.Bells)
+		lines = append(lines, fmt.Sprintf(texts.Advice.Later, expected, chance*100, owned.Bells))
+
+		if currentPrice > 0 && float64(currentPrice) >= expected {
+			lines = append(lines, texts.Advice.Sell)
+		} else {
+			lines = append(lines, texts.Advice.Hold)
+		}
+	}
+
+	rm := t.reply(m, strings.Join(lines, "\n\n"))
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+
+	return nil
+}
+
+// handleIslandsCmd triggers when the islands cmd is sent to a group
+func (t *Telegram) handleIslandsCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	if m.Private() {
+		t.send(m.Chat, texts.GroupOnly)
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	group, err := db.GetGroup(m.Chat)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Get the island prices known this week
+	islandPrices, err := db.GetGroupWeekIslandPrices(m.Chat)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	if len(islandPrices) == 0 {
+		rm := t.reply(m, texts.Islands.NoIslands)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Forecast every island
+	type islandForecast struct {
+		user     *User
+		week     *UserWeek
+		expected float64
+	}
+
+	now := time.Now()
+	halfDay := -1
+	forecasts := []*islandForecast{}
+
+	for _, islandPrice := range islandPrices {
+		week, errw := NewUserWeek(&islandPrice.User, group, now)
+		if errw != nil {
+			rm := t.reply(m, texts.InternalError)
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+		halfDay = week.HalfDay(now)
+
+		island := &islandForecast{user: &islandPrice.User, week: week}
+		if halfDay+1 < len(week.Prices) {
+			island.expected, _ = week.Forecast.MaxFrom(halfDay+1, 0)
+		}
+
+		forecasts = append(forecasts, island)
+	}
+
+	if halfDay+1 >= 12 {
+		rm := t.reply(m, texts.Islands.Closed)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Best islands first, unknown patterns last
+	sort.SliceStable(forecasts, func(i, j int) bool {
+		return forecasts[i].expected > forecasts[j].expected
+	})
+
+	reply := texts.Islands.Ranking + "\n"
+
+	for _, island := range forecasts {
+		reply += "\n<code>"
+
+		if island.user.Username != "" {
+			reply += fmt.Sprintf("@%s", island.user.Username)
+		} else {
+			reply += html.EscapeString(island.user.Name())
+		}
+
+		reply += "</code>: "
+
+		if len(island.week.Forecast.Patterns) == 0 {
+			reply += texts.Islands.Unknown
+			continue
+		}
+
+		peak := island.week.Forecast.PeakFrom(halfDay + 1)
+
+		reply += fmt.Sprintf(
+			texts.Islands.Island,
+			island.expected, texts.Bells,
+			TimeToShortDayAMPM(texts, island.week.Times[peak]),
+			island.week.Forecast.Probabilities[BigSpike]*100,
+		)
+	}
+
+	t.send(m.Chat, reply)
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m})
+
+	return nil
+}
+
+// handleLeaderboardCmd triggers when the leaderboard cmd is sent to a group
+func (t *Telegram) handleLeaderboardCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	if m.Private() {
+		t.send(m.Chat, texts.GroupOnly)
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	group, err := db.GetGroup(m.Chat)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	nowCfg, err := group.NowConfig()
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Rank this week, last week and all time
+	bowDate := nowCfg.With(time.Now().In(nowCfg.TimeLocation)).BeginningOfWeek()
+	nextBowDate := bowDate.AddDate(0, 0, 7)
+
+	windows := []struct {
+		title string
+		from  time.Time
+		to    time.Time
+	}{
+		{texts.Leaderboard.ThisWeek, bowDate, nextBowDate},
+		{texts.Leaderboard.LastWeek, bowDate.AddDate(0, 0, -7), bowDate},
+		{texts.Leaderboard.AllTime, time.Time{}, nextBowDate},
+	}
+
+	replyLines := []string{}
+	var allTime *Leaderboard
+
+	for _, window := range windows {
+		lb, errl := NewLeaderboard(group, window.from, window.to)
+		if errl != nil {
+			rm := t.reply(m, texts.InternalError)
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+		replyLines = append(replyLines, lb.Text(texts, window.title))
+		allTime = lb
+	}
+
+	if bestPrice := allTime.BestPriceText(texts, nowCfg.TimeLocation); bestPrice != "" {
+		replyLines = append(replyLines, bestPrice)
+	}
+
+	t.send(m.Chat, strings.Join(replyLines, "\n\n"))
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m})
+
+	return nil
+}
+
+// handleQuery triggers when the bot is used in inline mode, it answers with the user week in each of their groups
+func (t *Telegram) handleQuery(ctx tb.Context) error {
+	q := ctx.Query()
+	texts := t.texts(nil, q.Sender)
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("user_id", q.Sender.ID).Str("user_first_name", q.Sender.FirstName).
+		Str("user_last_name", q.Sender.LastName).Str("user_username", q.Sender.Username).
+		Str("query", q.Text).Msg("inline query")
+
+	user, err := db.GetUser(q.Sender)
+	if err != nil {
+		return nil
+	}
+
+	groups, err := db.GetUserGroups(q.Sender)
+	if err != nil {
+		return nil
+	}
+
+	// The query text filters the groups by title
+	filter := strings.ToLower(strings.TrimSpace(q.Text))
+	results := tb.Results{}
+
+	for _, group := range groups {
+		if filter != "" && !strings.Contains(strings.ToLower(group.Title), filter) {
+			continue
+		}
+
+		week, errw := NewUserWeek(user, group, time.Now())
+		if errw != nil || !week.HasPrices() {
+			continue
+		}
+
+		title := fmt.Sprintf(texts.Inline.Title, group.Title)
+		patterns := weekPatternsText(texts, week)
+		summary := fmt.Sprintf(texts.Inline.Summary, user.Mention(), group.Title) + "\n\n" + weekPricesText(texts, week) + "\n\n" + patterns
+
+		results = append(results, &tb.ArticleResult{
+			ResultBase:  tb.ResultBase{ID: fmt.Sprintf("%d-prices", group.ID), Content: &tb.InputTextMessageContent{Text: summary, ParseMode: tb.ModeHTML}},
+			Title:       title,
+			Description: texts.Inline.Prices,
+		})
+
+		// The chart needs to be uploaded somewhere before sharing it
+		if cacheChatID == 0 {
+			continue
+		}
+
+		nowCfg, errn := group.NowConfig()
+		if errn != nil {
+			continue
+		}
+
+		owned, erro := db.getUserWeekOwned(user, group)
+		if erro != nil {
+			continue
+		}
+
+		// Reuse the uploaded chart until the week changes
+		key := chartKey{userID: user.ID, groupID: group.ID}
+		version := chartVersion{texts: texts, title: user.String(), start: week.Times[0].Unix(), week: week.TurnipProphetURL(), bought: owned.Bells}
+
+		fileID := t.cachedChart(key, version)
+		if fileID == "" {
+			chart, errc := PricesChart(texts, user.String(), &week.Times, &week.Prices, owned.Bells, week.Forecast, nowCfg.TimeLocation)
+			if errc != nil {
+				continue
+			}
+
+			fileID = t.uploadPhoto(&tb.Photo{File: tb.FromReader(chart)})
+			if fileID == "" {
+				continue
+			}
+
+			t.setCachedChart(key, version, fileID)
+		}
+
+		results = append(results, &tb.PhotoResult{
+			ResultBase:  tb.ResultBase{ID: fmt.Sprintf("%d-chart", group.ID), ParseMode: tb.ModeHTML},
+			Cache:       fileID,
+			Title:       title,
+			Description: texts.Inline.Chart,
+			Caption:     patterns,
+		})
+	}
+
+	response := &tb.QueryResponse{Results: results, CacheTime: 60, IsPersonal: true}
+
+	if len(results) == 0 {
+		response.SwitchPMText = texts.Inline.NoPrices
+		response.SwitchPMParameter = "inline"
+	}
+
+	err = ctx.Answer(response)
+	if err != nil {
+		log.Error().Str("module", "telegram").Err(err).Msg("error answering inline query")
+	}
+
+	return nil
+}
+
+// handleTurnipsCmd triggers when the turnips cmd is sent to a group
+func (t *Telegram) handleTurnipsCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	if m.Private() {
+		t.send(m.Chat, texts.GroupOnly)
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	// Get owneds
+	owneds, err := db.GetGroupWeekOwned(m.Chat)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	var reply string
+	if len(owneds) == 0 {
+		reply = texts.Turnips.NoOwneds
+	} else {
+		reply = texts.Turnips.Owneds + "\n"
+		for _, owned := range owneds {
+			reply += "\n<code>"
+
+			if owned.User.Username != "" {
+				reply += fmt.Sprintf("@%s", owned.User.Username)
+			} else {
+				reply += owned.User.Name()
+			}
+
+			reply += fmt.Sprintf(
+				"</code>: <b>%v</b> x <b>%v</b> %s = <b>%v</b>",
+				owned.Units,
+				owned.Bells, texts.Bells,
+				owned.Units*owned.Bells,
+			)
+
+			if sold := owned.SoldUnits(); sold > 0 {
+				reply += fmt.Sprintf(texts.Turnips.Sold, sold, owned.RemainingUnits(), owned.RealizedProfits())
+			}
+		}
+	}
+
+	t.send(m.Chat, reply)
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m})
+
+	return nil
+}
+
+// handleDeleteCmd triggers when the delete cmd is sent to a group
+func (t *Telegram) handleDeleteCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	if m.Private() {
+		t.send(m.Chat, texts.GroupOnly)
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	// Check if the user is a group admin or a super admin
+	groupAdmin, err := t.isGroupAdmin(m.Chat, m.Sender)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	if !groupAdmin && !t.isSuperAdmin(m.Sender) {
+		rm := t.reply(m, texts.Unprivileged)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Validate the parameters
+	parameters := strings.Fields(m.Payload)
+	if len(parameters) != 1 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Delete.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	seconds, err := parseUint8(parameters[0])
+	if err != nil || seconds > 30 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Delete.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	err = db.ChangeGroupDeleteSeconds(m.Chat, seconds)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	var rm *tb.Message
+	if seconds > 0 {
+		rm = t.reply(m, fmt.Sprintf(texts.Delete.Done, seconds))
+	} else {
+		rm = t.reply(m, texts.Delete.Disabled)
+	}
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+
+	return nil
+}
+
+// groupMember returns the group member a moderation cmd refers to, named with the first parameter as @username or
+// replying to one of their messages, and the remaining parameters. The member is nil if unknown or not in the group.
+func (t *Telegram) groupMember(m *tb.Message, parameters []string) (*User, []string, error) {
+	if len(parameters) > 0 && strings.HasPrefix(parameters[0], "@") {
+		username := strings.TrimPrefix(parameters[0], "@")
+
+		users, err := db.GetGroupUsersByUsername(m.Chat, username)
+		if err != nil {
+			return nil, parameters[1:], err
+		}
+
+		// Saved usernames could be outdated, trust only the current one of a member
+		for _, user := range users {
+			current, errm := t.groupMemberOf(m.Chat, user.ID)
+			if errm != nil {
+				return nil, parameters[1:], errm
+			}
+
+			if current != nil && strings.EqualFold(current.Username, username) {
+				member, errg := db.GetUser(current)
+				return member, parameters[1:], errg
+			}
+		}
+
+		return nil, parameters[1:], nil
+	}
+
+	if m.ReplyTo != nil && m.ReplyTo.Sender != nil && !m.ReplyTo.Sender.IsBot {
+		current, err := t.groupMemberOf(m.Chat, m.ReplyTo.Sender.ID)
+		if err != nil || current == nil {
+			return nil, parameters, err
+		}
+
+		member, err := db.GetUser(current)
+		return member, parameters, err
+	}
+
+	return nil, parameters, nil
+}
+
+// replyMemberError replies why the member of a moderation cmd could not be found
+func (t *Telegram) replyMemberError(texts *Texts, m *tb.Message, parameters []string, err error) *tb.Message {
+	switch {
+	case err != nil:
+		return t.reply(m, texts.InternalError)
+	case len(parameters) > 0 && strings.HasPrefix(parameters[0], "@"):
+		return t.reply(m, fmt.Sprintf(texts.Member.Unknown, html.EscapeString(parameters[0])))
+	case m.ReplyTo != nil && m.ReplyTo.Sender != nil:
+		return t.reply(m, fmt.Sprintf(texts.Member.Unknown, html.EscapeString(m.ReplyTo.Sender.FirstName)))
+	}
+
+	return t.reply(m, texts.Member.Missing)
+}
+
+// handleFixCmd triggers when the fix cmd is sent to a group
+func (t *Telegram) handleFixCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	if m.Private() {
+		t.send(m.Chat, texts.GroupOnly)
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	// Check if the user is a group admin or a super admin
+	groupAdmin, err := t.isGroupAdmin(m.Chat, m.Sender)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	if !groupAdmin && !t.isSuperAdmin(m.Sender) {
+		rm := t.reply(m, texts.Unprivileged)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Find the member
+	parameters := strings.Fields(m.Payload)
+	member, parameters, err := t.groupMember(m, parameters)
+	if err != nil || member == nil {
+		rm := t.replyMemberError(texts, m, strings.Fields(m.Payload), err)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Validate the parameters
+	if len(parameters) < 2 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Fix.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	var (
+		record   AuditRecord
+		date     string
+		old, new AuditValues
+	)
+
+	switch {
+	case strings.EqualFold(parameters[0], texts.Clear.Buy):
+		if len(parameters) != 3 {
+			rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Fix.Params))
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+		units, err := parseUint32(parameters[1])
+		bells, err2 := parseUint32(parameters[2])
+		if err != nil || err2 != nil || bells < 90 || bells > 110 {
+			rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Fix.Params))
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+		if math.Mod(float64(units), 10) != 0 {
+			rm := t.reply(m, texts.Buy.UnitsModTen)
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+		newO, oldUnits, oldBells, err := db.SaveMemberWeekOwned(m.Sender, m.Chat, member, units, bells)
+		if err != nil {
+			rm := t.reply(m, texts.InternalError)
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+		record = AuditOwned
+		old = AuditValues{Exists: !newO, Units: oldUnits, Bells: oldBells}
+		new = AuditValues{Exists: true, Units: units, Bells: bells}
+
+	case strings.EqualFold(parameters[0], texts.Clear.Island):
+		if len(parameters) != 2 {
+			rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Fix.Params))
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+		bells, err := parseUint32(parameters[1])
+		if err != nil || bells < 90 || bells > 110 {
+			rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Fix.Params))
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+		newIP, oldBells, err := db.SaveMemberIslandPrice(m.Sender, m.Chat, member, bells)
+		if err != nil {
+			rm := t.reply(m, texts.InternalError)
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+		record = AuditIslandPrice
+		old = AuditValues{Exists: !newIP, Bells: oldBells}
+		new = AuditValues{Exists: true, Bells: bells}
+
+	default:
+		bells, err := parseUint32(parameters[0])
+		if err != nil || bells > 660 {
+			rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Fix.Params))
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+		dateStr := strings.Join(parameters[1:], " ")
+
+		newP, oldBells, priceDate, err := db.SaveMemberPrice(m.Sender, m.Chat, member, bells, dateStr)
+		if err != nil {
+			var rm *tb.Message
+
+			switch err {
+			case ErrDateParse:
+				rm = t.reply(m, fmt.Sprintf(texts.Sell.InvalidDate, dateStr))
+			case ErrBuyDay:
+				rm = t.reply(m, fmt.Sprintf(texts.Sell.NoMarketToday, priceDate, texts.Days[turnipSellDay]))
+			default:
+				rm = t.reply(m, texts.InternalError)
 			}
+
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+
+		record = AuditPrice
+		date = priceDate
+		old = AuditValues{Exists: !newP, Bells: oldBells}
+		new = AuditValues{Exists: true, Bells: bells}
+	}
+
+	change := fmt.Sprintf(texts.Audit.Change, auditRecordText(texts, record, date), auditValuesText(texts, record, old), auditValuesText(texts, record, new))
+
+	rm := t.reply(m, fmt.Sprintf(texts.Fix.Changed, member.Mention(), change))
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+
+	return nil
+}
+
+// handleRemoveCmd triggers when the remove cmd is sent to a group
+func (t *Telegram) handleRemoveCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	if m.Private() {
+		t.send(m.Chat, texts.GroupOnly)
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	// Check if the user is a group admin or a super admin
+	groupAdmin, err := t.isGroupAdmin(m.Chat, m.Sender)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	if !groupAdmin && !t.isSuperAdmin(m.Sender) {
+		rm := t.reply(m, texts.Unprivileged)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Find the member
+	parameters := strings.Fields(m.Payload)
+	member, parameters, err := t.groupMember(m, parameters)
+	if err != nil || member == nil {
+		rm := t.replyMemberError(texts, m, strings.Fields(m.Payload), err)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Validate the parameters
+	if len(parameters) == 0 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Remove.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	record := AuditPrice
+	dateStr := strings.Join(parameters, " ")
+
+	if len(parameters) == 1 && strings.EqualFold(parameters[0], texts.Clear.Buy) {
+		record = AuditOwned
+	} else if len(parameters) == 1 && strings.EqualFold(parameters[0], texts.Clear.Island) {
+		record = AuditIslandPrice
+	}
+
+	// Remove the record
+	date, err := db.ClearMemberRecord(m.Sender, m.Chat, member, record, dateStr)
+	if err != nil {
+		var rm *tb.Message
+
+		switch err {
+		case ErrDateParse:
+			rm = t.reply(m, fmt.Sprintf(texts.Sell.InvalidDate, dateStr))
+		case ErrNoRecord:
+			rm = t.reply(m, texts.Clear.NoRecord)
+		case ErrHasSales:
+			rm = t.reply(m, texts.Clear.HasSales)
+		default:
+			rm = t.reply(m, texts.InternalError)
+		}
+
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	rm := t.reply(m, fmt.Sprintf(texts.Remove.Removed, auditRecordText(texts, record, date), member.Mention()))
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+
+	return nil
+}
+
+// handleHistoryCmd triggers when the history cmd is sent to a group
+func (t *Telegram) handleHistoryCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	if m.Private() {
+		t.send(m.Chat, texts.GroupOnly)
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	// Check if the user is a group admin or a super admin
+	groupAdmin, err := t.isGroupAdmin(m.Chat, m.Sender)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	if !groupAdmin && !t.isSuperAdmin(m.Sender) {
+		rm := t.reply(m, texts.Unprivileged)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Find the member
+	parameters := strings.Fields(m.Payload)
+	member, parameters, err := t.groupMember(m, parameters)
+	if err != nil || member == nil {
+		rm := t.replyMemberError(texts, m, strings.Fields(m.Payload), err)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	if len(parameters) != 0 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.History.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	group, err := db.GetGroup(m.Chat)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	nowCfg, err := group.NowConfig()
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	entries, err := db.GetMemberAuditEntries(m.Chat, member, historyEntries)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	if len(entries) == 0 {
+		rm := t.reply(m, fmt.Sprintf(texts.History.NoEntries, member.Mention()))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Build the history, newest first
+	reply := fmt.Sprintf(texts.History.Title, member.Mention())
+
+	for _, entry := range entries {
+		author := html.EscapeString(entry.Author.Name())
+		if entry.AuthorID == entry.UserID {
+			author = texts.History.Self
+		}
+
+		reply += "\n" + fmt.Sprintf(texts.History.Entry, entry.ChangedAt.In(nowCfg.TimeLocation).Format("2006-01-02 15:04"), author, auditEntryText(texts, entry, nowCfg.TimeLocation))
+
+		if entry.Undone {
+			reply += " " + texts.History.Undone
+		}
+	}
+
+	rm := t.reply(m, reply)
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+
+	return nil
+}
+
+// handleExportCmd triggers when the export cmd is sent to a group
+func (t *Telegram) handleExportCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	if m.Private() {
+		t.send(m.Chat, texts.GroupOnly)
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	// Check if the user is a group admin or a super admin
+	groupAdmin, err := t.isGroupAdmin(m.Chat, m.Sender)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	if !groupAdmin && !t.isSuperAdmin(m.Sender) {
+		rm := t.reply(m, texts.Unprivileged)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Validate the parameters
+	parameters := strings.Fields(m.Payload)
+
+	format := exportFormatCSV
+	if len(parameters) == 1 {
+		format = strings.ToLower(parameters[0])
+	}
+
+	if len(parameters) > 1 || !validExportFormat(format) {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Export.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	group, err := db.GetGroup(m.Chat)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	export, err := NewGroupExport(group)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+
+	err = export.Write(buf, format)
+	if err != nil {
+		log.Error().Str("module", "telegram").Err(err).Msg("error writing export")
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// The export is a document worth keeping, it is not cleaned up
+	doc := &tb.Document{
+		File:     tb.FromReader(buf),
+		FileName: export.FileName(format),
+		MIME:     "text/" + format,
+		Caption:  fmt.Sprintf(texts.Export.Caption, len(export.Records), html.EscapeString(group.TZ)),
+	}
+
+	if format == exportFormatJSON {
+		doc.MIME = "application/json"
+	}
+
+	t.reply(m, doc)
+
+	return nil
+}
+
+// handleDChangeTZCmd triggers when the change TZ cmd is sent to a group
+func (t *Telegram) handleChangeTZCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	if m.Private() {
+		t.send(m.Chat, texts.GroupOnly)
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	// Check if the user is a group admin or a super admin
+	groupAdmin, err := t.isGroupAdmin(m.Chat, m.Sender)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	if !groupAdmin && !t.isSuperAdmin(m.Sender) {
+		rm := t.reply(m, texts.Unprivileged)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	// Validate the parameters
+	parameters := strings.Fields(m.Payload)
+	if len(parameters) != 1 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Delete.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	oldTZ, err := db.ChangeGroupTZ(m.Chat, parameters[0])
+	if err != nil {
+		var rm *tb.Message
+
+		if err == ErrInvalidTZ {
+			rm = t.reply(m, fmt.Sprintf(texts.ChangeTZ.Invalid, parameters[0]))
+		} else {
+			rm = t.reply(m, texts.InternalError)
 		}
+
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
 	}
 
-	// Gen all matching patterns
-	var forecast *Forecast = nil
-	if islandPrice != nil && islandPrice.Bells > 0 {
-		// Get last week forecast in order to be more accurate
-		var pwForecast *Forecast = nil
+	rm := t.reply(m, fmt.Sprintf(texts.ChangeTZ.Changed, oldTZ, parameters[0], texts.ChangeTZ.Cmd, oldTZ))
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+
+	return nil
+}
+
+// handleLangCmd triggers when the lang cmd is sent, in a group it changes the group language and in a private chat the
+// language override of the user
+func (t *Telegram) handleLangCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
 
-		pwTime := time.Now().AddDate(0, 0, -7)
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
 
-		pwPrices, errp := db.GetUserWeekPrices(m.Sender, m.Chat, pwTime)
-		if errp != nil {
+	// Check if the user is a group admin or a super admin
+	if !m.Private() {
+		groupAdmin, err := t.isGroupAdmin(m.Chat, m.Sender)
+		if err != nil {
 			rm := t.reply(m, texts.InternalError)
 			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 			return nil
 		}
 
-		pwIslandPrice, errp := db.GetUserIslandPriceByDate(m.Sender, m.Chat, pwTime)
-		if errp != nil {
-			rm := t.reply(m, texts.InternalError)
+		if !groupAdmin && !t.isSuperAdmin(m.Sender) {
+			rm := t.reply(m, texts.Unprivileged)
 			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 			return nil
 		}
+	}
 
-		// If last week there were no prices or no island price we skip the last week forecast
-		if len(pwPrices) > 0 && islandPrice != nil && islandPrice.Bells > 0 {
-			pwTimes := [12]time.Time{}
-			pwBuyPrices := [12]uint32{}
-
-			pwInitDate := groupNow.With(pwTime.In(groupNow.TimeLocation)).BeginningOfWeek().Add(time.Hour * 24)
+	// Without parameters list the available languages
+	parameters := strings.Fields(m.Payload)
+	if len(parameters) == 0 {
+		langLines := []string{texts.Lang.Available}
+		for _, lang := range availableLangs() {
+			langLines = append(langLines, fmt.Sprintf(texts.Lang.Lang, lang, html.EscapeString(langs[lang].LangName)))
+		}
 
-			for i := 0; i < 12; i++ {
-				pwTimes[i] = pwInitDate.Add(time.Hour * 12 * time.Duration(i))
-			}
+		rm := t.reply(m, strings.Join(langLines, "\n"))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
 
-			for _, price := range pwPrices {
-				for i := range pwTimes {
-					if price.Date.Equal(pwTimes[i]) {
-						pwBuyPrices[i] = price.Bells
-						break
-					}
-				}
-			}
+	if len(parameters) != 1 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, fmt.Sprintf(texts.Lang.Params, texts.Off)))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
 
-			pwForecast, err = NewForecast(pwIslandPrice.Bells, pwBuyPrices, nil)
-			if err != nil {
-				rm := t.reply(m, texts.InternalError)
-				t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
-				return nil
-			}
-		}
+	// Off goes back to the default language
+	lang := strings.ToLower(parameters[0])
+	if strings.EqualFold(lang, texts.Off) {
+		lang = ""
+	} else if _, ok := langs[lang]; !ok || lang == defaultLangFile {
+		rm := t.reply(m, fmt.Sprintf(texts.Lang.Invalid, html.EscapeString(parameters[0]), texts.Lang.Cmd))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
 
-		// Get this week forecast
-		forecast, err = NewForecast(islandPrice.Bells, buyPrices, pwForecast)
-		if err != nil {
-			rm := t.reply(m, texts.InternalError)
-			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
-			return nil
-		}
+	var err error
+	if m.Private() {
+		err = db.ChangeUserLang(m.Sender, lang)
+	} else {
+		err = db.ChangeGroupLang(m.Chat, lang)
 	}
 
-	// Generate chart
-	chart, err := PricesChart(user.String(), &times, &buyPrices, owned.Bells, forecast, groupNow.TimeLocation)
 	if err != nil {
 		rm := t.reply(m, texts.InternalError)
 		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 		return nil
 	}
 
-	// Add pattern info as image caption
-	var caption string
+	// The group menu follows the group language
+	if !m.Private() {
+		t.setChatCommands(m.Chat, lang)
+	}
 
-	if islandPrice == nil || islandPrice.Bells == 0 {
-		caption += texts.Patterns.NoIslandPrice
-	} else if len(forecast.Patterns) == 0 {
-		caption += texts.Patterns.Unknown
-	} else {
-		caption += texts.Patterns.Matching
-
-		for pat, prob := range forecast.Probabilities {
-			var pName string
-			var pDesc string
-
-			if pat == Random {
-				pName = texts.Patterns.Random.Name
-				pDesc = texts.Patterns.Random.Desc
-			} else if pat == BigSpike {
-				pName = texts.Patterns.BigSpike.Name
-				pDesc = texts.Patterns.BigSpike.Desc
-			} else if pat == Falling {
-				pName = texts.Patterns.Falling.Name
-				pDesc = texts.Patterns.Falling.Desc
-			} else if pat == SmallSpike {
-				pName = texts.Patterns.SmallSpike.Name
-				pDesc = texts.Patterns.SmallSpike.Desc
-			}
+	// Reply in the new language
+	texts = t.texts(m.Chat, m.Sender)
 
-			caption += fmt.Sprintf("\n- <b>%s</b> <i>(%.2f%%)</i>: %s", pName, prob*100, pDesc)
-		}
+	var rm *tb.Message
+	if lang == "" {
+		rm = t.reply(m, texts.Lang.Reset)
+	} else {
+		rm = t.reply(m, fmt.Sprintf(texts.Lang.Changed, texts.LangName))
 	}
 
-	t.send(m.Chat, &tb.Photo{File: tb.FromReader(chart), Caption: caption})
-	t.cleanupChatMsgs(m.Chat, []*tb.Message{m})
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 
 	return nil
 }
 
-// handleTurnipsCmd triggers when the turnips cmd is sent to a group
-func (t *Telegram) handleTurnipsCmd(ctx tb.Context) error {
+// handleRemindersCmd triggers when the reminders cmd is sent to a group
+func (t *Telegram) handleRemindersCmd(ctx tb.Context) error {
 	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
 	if m.Private() {
 		t.send(m.Chat, texts.GroupOnly)
 		return nil
@@ -643,46 +2839,72 @@ func (t *Telegram) handleTurnipsCmd(ctx tb.Context) error {
 		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
 		Msg(m.Text)
 
-	// Get owneds
-	owneds, err := db.GetGroupWeekOwned(m.Chat)
+	// Check if the user is a group admin or a super admin
+	groupAdmin, err := t.isGroupAdmin(m.Chat, m.Sender)
 	if err != nil {
 		rm := t.reply(m, texts.InternalError)
 		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 		return nil
 	}
 
-	var reply string
-	if len(owneds) == 0 {
-		reply = texts.Turnips.NoOwneds
-	} else {
-		reply = texts.Turnips.Owneds + "\n"
-		for _, owned := range owneds {
-			reply += "\n<code>"
+	if !groupAdmin && !t.isSuperAdmin(m.Sender) {
+		rm := t.reply(m, texts.Unprivileged)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
 
-			if owned.User.Username != "" {
-				reply += fmt.Sprintf("@%s", owned.User.Username)
-			} else {
-				reply += owned.User.Name()
+	// Validate the parameters
+	parameters := strings.Fields(m.Payload)
+	if len(parameters) == 0 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Reminders.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	clocks := []string{}
+	if len(parameters) != 1 || !strings.EqualFold(parameters[0], texts.Off) {
+		seen := map[string]bool{}
+
+		for _, param := range parameters {
+			clock, errc := parseClock(param)
+			if errc != nil {
+				rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Reminders.Params))
+				t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+				return nil
 			}
 
-			reply += fmt.Sprintf(
-				"</code>: <b>%v</b> x <b>%v</b> %s = <b>%v</b>",
-				owned.Units,
-				owned.Bells, texts.Bells,
-				owned.Units*owned.Bells,
-			)
+			if !seen[clock] {
+				seen[clock] = true
+				clocks = append(clocks, clock)
+			}
 		}
+
+		sort.Strings(clocks)
 	}
 
-	t.send(m.Chat, reply)
-	t.cleanupChatMsgs(m.Chat, []*tb.Message{m})
+	err = db.ChangeGroupSellReminders(m.Chat, clocks)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	var rm *tb.Message
+	if len(clocks) > 0 {
+		rm = t.reply(m, fmt.Sprintf(texts.Reminders.Changed, strings.Join(clocks, ", ")))
+	} else {
+		rm = t.reply(m, texts.Reminders.Disabled)
+	}
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 
 	return nil
 }
 
-// handleDeleteCmd triggers when the delete cmd is sent to a group
-func (t *Telegram) handleDeleteCmd(ctx tb.Context) error {
+// handleBuyReminderCmd triggers when the buy reminder cmd is sent to a group
+func (t *Telegram) handleBuyReminderCmd(ctx tb.Context) error {
 	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
 	if m.Private() {
 		t.send(m.Chat, texts.GroupOnly)
 		return nil
@@ -712,19 +2934,67 @@ func (t *Telegram) handleDeleteCmd(ctx tb.Context) error {
 	// Validate the parameters
 	parameters := strings.Fields(m.Payload)
 	if len(parameters) != 1 {
-		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Delete.Params))
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.BuyReminder.Params))
 		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 		return nil
 	}
 
-	seconds, err := parseUint8(parameters[0])
-	if err != nil || seconds > 30 {
-		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Delete.Params))
+	clock := ""
+	if !strings.EqualFold(parameters[0], texts.Off) {
+		clock, err = parseClock(parameters[0])
+		if err != nil {
+			rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.BuyReminder.Params))
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
+		}
+	}
+
+	err = db.ChangeGroupBuyReminder(m.Chat, clock)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
 		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 		return nil
 	}
 
-	err = db.ChangeGroupDeleteSeconds(m.Chat, seconds)
+	var rm *tb.Message
+	if clock != "" {
+		rm = t.reply(m, fmt.Sprintf(texts.BuyReminder.Changed, clock))
+	} else {
+		rm = t.reply(m, texts.BuyReminder.Disabled)
+	}
+	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+
+	return nil
+}
+
+// handleSpikeAlertsCmd triggers when the spike alerts cmd is sent to a group
+func (t *Telegram) handleSpikeAlertsCmd(ctx tb.Context) error {
+	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
+	if m.Private() {
+		t.send(m.Chat, texts.GroupOnly)
+		return nil
+	}
+
+	log.Info().
+		Str("module", "telegram").
+		Int64("chat_id", m.Chat.ID).Str("chat_title", m.Chat.Title).
+		Int64("user_id", m.Sender.ID).Str("user_first_name", m.Sender.FirstName).
+		Str("user_last_name", m.Sender.LastName).Str("user_username", m.Sender.Username).
+		Msg(m.Text)
+
+	// Validate the parameters
+	parameters := strings.Fields(m.Payload)
+	if len(parameters) != 1 || (!strings.EqualFold(parameters[0], texts.On) && !strings.EqualFold(parameters[0], texts.Off)) {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.SpikeAlerts.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
+
+	enabled := strings.EqualFold(parameters[0], texts.On)
+
+	err := db.ChangeUserAlerts(m.Sender, enabled)
 	if err != nil {
 		rm := t.reply(m, texts.InternalError)
 		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
@@ -732,19 +3002,21 @@ func (t *Telegram) handleDeleteCmd(ctx tb.Context) error {
 	}
 
 	var rm *tb.Message
-	if seconds > 0 {
-		rm = t.reply(m, fmt.Sprintf(texts.Delete.Done, seconds))
+	if enabled {
+		rm = t.reply(m, texts.SpikeAlerts.Enabled)
 	} else {
-		rm = t.reply(m, texts.Delete.Disabled)
+		rm = t.reply(m, texts.SpikeAlerts.Disabled)
 	}
 	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 
 	return nil
 }
 
-// handleDChangeTZCmd triggers when the change TZ cmd is sent to a group
-func (t *Telegram) handleChangeTZCmd(ctx tb.Context) error {
+// handleAlertsCmd triggers when the alerts cmd is sent to a group
+func (t *Telegram) handleAlertsCmd(ctx tb.Context) error {
 	m := ctx.Message()
+	texts := t.texts(m.Chat, m.Sender)
+
 	if m.Private() {
 		t.send(m.Chat, texts.GroupOnly)
 		return nil
@@ -773,27 +3045,42 @@ func (t *Telegram) handleChangeTZCmd(ctx tb.Context) error {
 
 	// Validate the parameters
 	parameters := strings.Fields(m.Payload)
-	if len(parameters) != 1 {
-		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Delete.Params))
+	if len(parameters) != 1 && len(parameters) != 2 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Alerts.Params))
 		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 		return nil
 	}
 
-	oldTZ, err := db.ChangeGroupTZ(m.Chat, parameters[0])
-	if err != nil {
-		var rm *tb.Message
+	bells, err := parseUint32(parameters[0])
+	if err != nil || bells > 660 {
+		rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Alerts.Params))
+		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+		return nil
+	}
 
-		if err == ErrInvalidTZ {
-			rm = t.reply(m, fmt.Sprintf(texts.ChangeTZ.Invalid, parameters[0]))
-		} else {
-			rm = t.reply(m, texts.InternalError)
+	var margin uint16 = 0
+	if len(parameters) == 2 {
+		margin, err = parseUint16(strings.TrimSuffix(parameters[1], "%"))
+		if err != nil || margin > 500 {
+			rm := t.reply(m, fmt.Sprintf("%s %s", texts.InvalidParams, texts.Alerts.Params))
+			t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
+			return nil
 		}
+	}
 
+	err = db.ChangeGroupAlerts(m.Chat, bells, margin)
+	if err != nil {
+		rm := t.reply(m, texts.InternalError)
 		t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 		return nil
 	}
 
-	rm := t.reply(m, fmt.Sprintf(texts.ChangeTZ.Changed, oldTZ, parameters[0], texts.ChangeTZ.Cmd, oldTZ))
+	var rm *tb.Message
+	if bells > 0 || margin > 0 {
+		rm = t.reply(m, fmt.Sprintf(texts.Alerts.Changed, bells, margin))
+	} else {
+		rm = t.reply(m, texts.Alerts.Disabled)
+	}
 	t.cleanupChatMsgs(m.Chat, []*tb.Message{m, rm})
 
 	return nil
@@ -0,0 +1,254 @@
+// Copyright (c) 2020 Sergio Conde skgsergio@gmail.com
+//
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU General Public License as published by the Free Software
+// Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY
+// WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+// PARTICULAR PURPOSE. See the GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along with
+// this program. If not, see <https://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/now"
+)
+
+var (
+	// ErrImportQuery is returned when a Turnip Prophet query string is invalid
+	ErrImportQuery = errors.New("invalid turnip prophet query")
+
+	// ErrImportHeader is returned when a CSV import lacks the required columns
+	ErrImportHeader = errors.New("csv import requires record, date and bells columns")
+)
+
+// ImportRowError is returned when a row of a CSV import is invalid, rows start at 1 for the header
+type ImportRowError struct {
+	Row int
+}
+
+// Error returns the error message
+func (e *ImportRowError) Error() string {
+	return fmt.Sprintf("invalid csv import row %d", e.Row)
+}
+
+// ImportRecord is a Price, Owned or IslandPrice to import, the date is already normalized to the record one
+type ImportRecord struct {
+	Record AuditRecord
+	Date   time.Time
+	Values AuditValues
+}
+
+// UserImport is the data of an User to import in a Group, known patterns only use the Pattern and Date fields
+type UserImport struct {
+	Records       []ImportRecord
+	KnownPatterns []KnownPattern
+}
+
+// newImportRecord validates the values of a record and normalizes its date, prices to the half-day and the rest to
+// the beginning of the week
+func newImportRecord(record AuditRecord, date time.Time, values AuditValues, nowCfg *now.Config) (ImportRecord, bool) {
+	values.Exists = true
+
+	// Records of half-days and weeks to come can't be known
+	now := time.Now()
+	bowNow := nowCfg.With(now.In(nowCfg.TimeLocation)).BeginningOfWeek()
+
+	switch record {
+	case AuditPrice:
+		date = currentHalfDay(nowCfg, date)
+		if values.Bells > 660 || date.Weekday() == turnipSellDay || date.After(currentHalfDay(nowCfg, now)) {
+			return ImportRecord{}, false
+		}
+
+	case AuditOwned:
+		date = nowCfg.With(date.In(nowCfg.TimeLocation)).BeginningOfWeek()
+		if values.Bells < 90 || values.Bells > 110 || math.Mod(float64(values.Units), 10) != 0 || date.After(bowNow) {
+			return ImportRecord{}, false
+		}
+
+	case AuditIslandPrice:
+		date = nowCfg.With(date.In(nowCfg.TimeLocation)).BeginningOfWeek()
+		if values.Bells < 90 || values.Bells > 110 || date.After(bowNow) {
+			return ImportRecord{}, false
+		}
+
+	default:
+		return ImportRecord{}, false
+	}
+
+	return ImportRecord{Record: record, Date: date, Values: values}, true
+}
+
+// parseTurnipProphetQuery parses a Turnip Prophet share URL or its query string as the week the time belongs to.
+// The prices are the island price followed by the sell prices from Monday AM separated by dots, empty if unknown, and
+// the pattern is the one of the previous week.
+func parseTurnipProphetQuery(query string, nowCfg *now.Config, t time.Time) (*UserImport, error) {
+	if i := strings.Index(query, "?"); i >= 0 {
+		query = query[i+1:]
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, ErrImportQuery
+	}
+
+	prices := strings.Split(values.Get("prices"), ".")
+	if len(prices) < 1 || len(prices) > 13 {
+		return nil, ErrImportQuery
+	}
+
+	data := &UserImport{}
+	bowDate := nowCfg.With(t.In(nowCfg.TimeLocation)).BeginningOfWeek()
+
+	for i, price := range prices {
+		if price == "" {
+			continue
+		}
+
+		bells, errp := parseUint32(price)
+		if errp != nil {
+			return nil, ErrImportQuery
+		}
+
+		record := ImportRecord{}
+		ok := false
+
+		if i == 0 {
+			record, ok = newImportRecord(AuditIslandPrice, bowDate, AuditValues{Bells: bells, FirstBuy: values.Get("first") == "true"}, nowCfg)
+		} else {
+			date := weekHalfDay(nowCfg, bowDate, i-1)
+			if date.After(currentHalfDay(nowCfg, time.Now())) {
+				return nil, &FutureHalfDayError{Date: date}
+			}
+
+			record, ok = newImportRecord(AuditPrice, date, AuditValues{Bells: bells}, nowCfg)
+		}
+
+		if !ok {
+			return nil, ErrImportQuery
+		}
+
+		data.Records = append(data.Records, record)
+	}
+
+	// Unknown patterns are negative
+	if pattern := values.Get("pattern"); pattern != "" {
+		p, errp := strconv.Atoi(pattern)
+		if errp != nil || p > int(SmallSpike) {
+			return nil, ErrImportQuery
+		}
+
+		if p >= 0 {
+			data.KnownPatterns = append(data.KnownPatterns, KnownPattern{Pattern: PatternType(p), Date: bowDate.AddDate(0, 0, -7)})
+		}
+	}
+
+	if len(data.Records) == 0 && len(data.KnownPatterns) == 0 {
+		return nil, ErrImportQuery
+	}
+
+	return data, nil
+}
+
+// parseImportCSV parses a CSV import with the columns of an export, record, date and bells are required and the rest
+// are optional. Dates are RFC 3339 or YYYY-MM-DD AM/PM in the group timezone and rows of other users are skipped.
+func parseImportCSV(r io.Reader, nowCfg *now.Config, userID int64) (*UserImport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, ErrImportHeader
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range []string{"record", "date", "bells"} {
+		if _, ok := columns[name]; !ok {
+			return nil, ErrImportHeader
+		}
+	}
+
+	data := &UserImport{}
+
+	for row := 2; ; row++ {
+		fields, errr := reader.Read()
+		if errr == io.EOF {
+			break
+		}
+
+		if errr != nil {
+			return nil, &ImportRowError{Row: row}
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+
+			return ""
+		}
+
+		if id := field("user_id"); id != "" && id != strconv.FormatInt(userID, 10) {
+			continue
+		}
+
+		date, errr := time.Parse(time.RFC3339, field("date"))
+		if errr != nil {
+			date, errr = nowCfg.Parse(field("date"))
+			if errr != nil {
+				return nil, &ImportRowError{Row: row}
+			}
+		}
+
+		values := AuditValues{}
+
+		if units := field("units"); units != "" {
+			values.Units, errr = parseUint32(units)
+			if errr != nil {
+				return nil, &ImportRowError{Row: row}
+			}
+		}
+
+		values.Bells, errr = parseUint32(field("bells"))
+		if errr != nil {
+			return nil, &ImportRowError{Row: row}
+		}
+
+		if firstBuy := field("first_buy"); firstBuy != "" {
+			values.FirstBuy, errr = strconv.ParseBool(firstBuy)
+			if errr != nil {
+				return nil, &ImportRowError{Row: row}
+			}
+		}
+
+		record, ok := newImportRecord(AuditRecord(strings.ToLower(field("record"))), date, values, nowCfg)
+		if !ok {
+			return nil, &ImportRowError{Row: row}
+		}
+
+		data.Records = append(data.Records, record)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2020 Sergio Conde skgsergio@gmail.com
+//
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU General Public License as published by the Free Software
+// Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY
+// WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+// PARTICULAR PURPOSE. See the GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along with
+// this program. If not, see <https://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LeaderboardEntry is the best achievable profits of an User
+type LeaderboardEntry struct {
+	User    User
+	Profits int64
+}
+
+// Leaderboard ranks the Users of a Group by the profits they could have made selling all their turnips at the best
+// price recorded in the Group each week
+type Leaderboard struct {
+	Entries   []*LeaderboardEntry
+	BestPrice *Price
+}
+
+// NewLeaderboard returns the Leaderboard of a Group for the weeks between two dates, end excluded
+func NewLeaderboard(g *Group, from time.Time, to time.Time) (*Leaderboard, error) {
+	lb := Leaderboard{
+		Entries: []*LeaderboardEntry{},
+	}
+
+	owneds, err := db.getGroupOwneds(g, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	lb.BestPrice, err = db.getGroupBestPrice(g, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Owneds are stored at the beginning of the week, so the best price is searched in the week after it
+	weekBestPrices := map[int64]*Price{}
+	entries := map[int64]*LeaderboardEntry{}
+
+	for _, owned := range owneds {
+		if owned.Units == 0 {
+			continue
+		}
+
+		bestPrice, ok := weekBestPrices[owned.Date.Unix()]
+		if !ok {
+			bestPrice, err = db.getGroupBestPrice(g, owned.Date, owned.Date.AddDate(0, 0, 7))
+			if err != nil {
+				return nil, err
+			}
+
+			weekBestPrices[owned.Date.Unix()] = bestPrice
+		}
+
+		if bestPrice == nil {
+			continue
+		}
+
+		entry, ok := entries[owned.UserID]
+		if !ok {
+			entry = &LeaderboardEntry{User: owned.User}
+			entries[owned.UserID] = entry
+			lb.Entries = append(lb.Entries, entry)
+		}
+
+		entry.Profits += int64(owned.Units)*int64(bestPrice.Bells) - int64(owned.Units)*int64(owned.Bells)
+	}
+
+	sort.SliceStable(lb.Entries, func(i, j int) bool {
+		return lb.Entries[i].Profits > lb.Entries[j].Profits
+	})
+
+	return &lb, nil
+}
+
+// Text returns the Leaderboard ranking as a message in a language with the given title
+func (lb *Leaderboard) Text(texts *Texts, title string) string {
+	lines := []string{title}
+
+	if len(lb.Entries) == 0 {
+		lines = append(lines, texts.Leaderboard.NoEntries)
+	}
+
+	for i, entry := range lb.Entries {
+		lines = append(lines, fmt.Sprintf(texts.Leaderboard.Entry, i+1, html.EscapeString(entry.User.Name()), entry.Profits, texts.Bells))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// BestPriceText returns the highest price of the Leaderboard as a message in a language, empty if there is none
+func (lb *Leaderboard) BestPriceText(texts *Texts, location *time.Location) string {
+	if lb.BestPrice == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		texts.Leaderboard.BestPrice,
+		lb.BestPrice.Bells, texts.Bells, html.EscapeString(lb.BestPrice.User.Name()),
+		lb.BestPrice.Date.In(location).Format("2006-01-02"),
+	)
+}
@@ -30,11 +30,13 @@ const (
 )
 
 var (
-	defaultTZ   string    = "UTC"
-	bot         *Telegram = nil
-	db          *Database = nil
-	texts       *Texts    = nil
-	superAdmins []int64   = []int64{}
+	defaultTZ    string            = "UTC"
+	bot          *Telegram         = nil
+	db           *Database         = nil
+	langs        map[string]*Texts = map[string]*Texts{}
+	defaultTexts *Texts            = nil
+	superAdmins  []int64           = []int64{}
+	cacheChatID  int64             = 0
 )
 
 func main() {
@@ -54,32 +56,48 @@ func main() {
 
 	log.Logger = log.With().Caller().Logger()
 
+	// Subcommands for operators only need the database
+	subcommand := ""
+	if len(os.Args) > 1 {
+		subcommand = os.Args[1]
+	}
+
 	// Check required env vars
-	for _, envVar := range []string{
-		"MERCANABO_TOKEN",
+	envVars := []string{
 		"POSTGRES_HOST",
 		"POSTGRES_PORT",
 		"POSTGRES_USER",
 		"POSTGRES_PASSWORD",
 		"POSTGRES_DB",
 		"POSTGRES_SSLMODE",
-	} {
+	}
+
+	if subcommand == "" {
+		envVars = append(envVars, "MERCANABO_TOKEN")
+	}
+
+	for _, envVar := range envVars {
 		if os.Getenv(envVar) == "" {
 			log.Fatal().Str("module", "main").Str("envvar", envVar).Msg("missing environment variable")
 		}
 	}
 
-	// Load bot texts
+	// Load bot texts of every language
 	if envl := os.Getenv("MERCANABO_LANG"); envl != "" {
 		lang = envl
 	}
 
-	texts, err = LoadTexts(lang)
+	langs, err = LoadAllTexts()
 	if err != nil {
-		log.Fatal().Str("module", "main").Err(err).Msg("failed loading texts file")
+		log.Fatal().Str("module", "main").Err(err).Msg("failed loading texts files")
+	}
+
+	defaultTexts = langs[lang]
+	if defaultTexts == nil {
+		log.Fatal().Str("module", "main").Str("lang", lang).Msg("missing texts file for default language")
 	}
 
-	log.Info().Str("module", "main").Str("lang", lang).Msg("loaded texts")
+	log.Info().Str("module", "main").Str("lang", lang).Strs("langs", availableLangs()).Msg("loaded texts")
 
 	// Load default time zone
 	if envtz := os.Getenv("MERCANABO_DEFAULT_TZ"); envtz != "" {
@@ -107,6 +125,17 @@ func main() {
 
 	log.Info().Str("module", "main").Ints64("user_ids", superAdmins).Msg("loaded superadmins")
 
+	// Load the chat used to upload images for inline results
+	if envcc := os.Getenv("MERCANABO_CACHE_CHAT"); envcc != "" {
+		cacheChatID, err = parseInt64(envcc)
+
+		if err != nil {
+			log.Fatal().Str("module", "main").Err(err).Msg("failed parsing cache chat")
+		}
+	}
+
+	log.Info().Str("module", "main").Int64("chat_id", cacheChatID).Msg("loaded cache chat")
+
 	// Connecto to the DB
 	db, err = OpenDB(
 		os.Getenv("POSTGRES_HOST"),
@@ -124,6 +153,19 @@ func main() {
 
 	db.SetupDB()
 
+	// Run the subcommand instead of the bot
+	switch subcommand {
+	case "":
+	case "export":
+		if err = runExport(os.Args[2:]); err != nil {
+			log.Fatal().Str("module", "main").Err(err).Msg("failed exporting group")
+		}
+
+		return
+	default:
+		log.Fatal().Str("module", "main").Str("subcommand", subcommand).Msg("unknown subcommand")
+	}
+
 	// Create bot
 	bot, err = NewBot(os.Getenv("MERCANABO_TOKEN"))
 
@@ -131,6 +173,10 @@ func main() {
 		log.Fatal().Str("module", "telegram").Err(err).Msg("failed bot instantiaion")
 	}
 
+	// Start the scheduler
+	scheduler := NewScheduler(bot)
+	go scheduler.Start()
+
 	// Start the bot
 	bot.Start()
 }
@@ -16,6 +16,9 @@
 package main
 
 import (
+	"fmt"
+	"html"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -24,11 +27,21 @@ import (
 )
 
 // Group represents a Telegram group
+// SellReminders is a comma separated list of HH:MM times to remind missing prices from Monday to Saturday and
+// BuyReminder is a HH:MM time to remind buying on Sunday, both are disabled when empty.
+// AlertBells and AlertMargin are the min price and the percentage over every purchase price that trigger a high
+// price alert, both are disabled when 0.
+// Lang is the language of the bot texts in the group, the default one when empty.
 type Group struct {
 	ID            int64  `gorm:"PRIMARY_KEY;NOT NULL"`
 	Title         string `gorm:"NOT NULL;DEFAULT:''"`
 	TZ            string `gorm:"NOT NULL;DEFAULT:'UTC'"`
 	DeleteSeconds uint8  `gorm:"NOT NULL;DEFAULT:0"`
+	SellReminders string `gorm:"NOT NULL;DEFAULT:''"`
+	BuyReminder   string `gorm:"NOT NULL;DEFAULT:''"`
+	AlertBells    uint32 `gorm:"NOT NULL;DEFAULT:0"`
+	AlertMargin   uint16 `gorm:"NOT NULL;DEFAULT:0"`
+	Lang          string `gorm:"NOT NULL;DEFAULT:''"`
 }
 
 // NowConfig returns a now.Config with the group timezone
@@ -46,12 +59,26 @@ func (g *Group) NowConfig() (*now.Config, error) {
 	}, nil
 }
 
+// SellReminderTimes returns the list of sell reminder times
+func (g *Group) SellReminderTimes() []string {
+	if g.SellReminders == "" {
+		return []string{}
+	}
+
+	return strings.Split(g.SellReminders, ",")
+}
+
 // User represents a Telegram user
+// Lang overrides the language of the bot texts for the User, the group one is used when empty.
 type User struct {
 	ID        int64  `gorm:"PRIMARY_KEY;NOT NULL"`
 	FirstName string `gorm:"NOT NULL;DEFAULT:''"`
 	LastName  string `gorm:"DEFAULT:''"`
 	Username  string `gorm:"DEFAULT:''"`
+	NoAlerts  bool   `gorm:"NOT NULL;DEFAULT:false"`
+
+	DashboardGroupID int64  `gorm:"NOT NULL;DEFAULT:0"`
+	Lang             string `gorm:"NOT NULL;DEFAULT:''"`
 }
 
 // Name returns the full name of the User
@@ -65,6 +92,15 @@ func (u *User) Name() string {
 	return name
 }
 
+// Mention returns the username or a link to the user if there is no username
+func (u *User) Mention() string {
+	if u.Username == "" {
+		return fmt.Sprintf("<a href=\"tg://user?id=%v\">%s</a>", u.ID, html.EscapeString(u.FirstName))
+	}
+
+	return "@" + u.Username
+}
+
 // String returns the full name and username (if user has)
 func (u *User) String() string {
 	if u.Username == "" {
@@ -96,18 +132,117 @@ type Owned struct {
 	Units   uint32    `gorm:"NOT NULL"`
 	Bells   uint32    `gorm:"NOT NULL"`
 	Date    time.Time `gorm:"INDEX;NOT NULL"`
+	Sales   []Sale    `gorm:"FOREIGNKEY:OwnedID"`
+}
+
+// SoldUnits returns how many turnips have been sold
+func (o *Owned) SoldUnits() uint32 {
+	var units uint32 = 0
+
+	for _, sale := range o.Sales {
+		units += sale.Units
+	}
+
+	return units
+}
+
+// RemainingUnits returns how many turnips are left to sell
+func (o *Owned) RemainingUnits() uint32 {
+	sold := o.SoldUnits()
+
+	if sold > o.Units {
+		return 0
+	}
+
+	return o.Units - sold
+}
+
+// RealizedProfits returns the profits of the sold turnips
+func (o *Owned) RealizedProfits() int64 {
+	var profits int64 = 0
+
+	for _, sale := range o.Sales {
+		profits += int64(sale.Units)*int64(sale.Bells) - int64(sale.Units)*int64(o.Bells)
+	}
+
+	return profits
+}
+
+// Sale represents turnips from an Owned that an User sold at a price in a given half day.
+// Sales aren't audited, ChangedAt is set by the database so undo can tell if they happened after a change.
+type Sale struct {
+	ID        uint64    `gorm:"PRIMARY_KEY;AUTO_INCREMENT;NOT NULL"`
+	OwnedID   uint64    `gorm:"INDEX;NOT NULL"`
+	Units     uint32    `gorm:"NOT NULL"`
+	Bells     uint32    `gorm:"NOT NULL"`
+	Date      time.Time `gorm:"INDEX;NOT NULL"`
+	ChangedAt time.Time `gorm:"NOT NULL;DEFAULT:now()"`
 }
 
 // IslandPrice is the price of the User island.
 // This allows to buy in other island not your own but storing your island price that is important for the forecasts.
+// FirstBuy flags the first week buying in the User island, that week the game forces the small spike pattern.
 type IslandPrice struct {
-	ID      uint64    `gorm:"PRIMARY_KEY;AUTO_INCREMENT;NOT NULL"`
-	GroupID int64     `gorm:"INDEX;NOT NULL"`
-	Group   Group     `gorm:"FOREIGNKEY:GroupID"`
-	UserID  int64     `gorm:"INDEX;NOT NULL"`
-	User    User      `gorm:"FOREIGNKEY:UserID"`
-	Bells   uint32    `gorm:"NOT NULL;DEFAULT:0"`
-	Date    time.Time `gorm:"INDEX;NOT NULL"`
+	ID       uint64    `gorm:"PRIMARY_KEY;AUTO_INCREMENT;NOT NULL"`
+	GroupID  int64     `gorm:"INDEX;NOT NULL"`
+	Group    Group     `gorm:"FOREIGNKEY:GroupID"`
+	UserID   int64     `gorm:"INDEX;NOT NULL"`
+	User     User      `gorm:"FOREIGNKEY:UserID"`
+	Bells    uint32    `gorm:"NOT NULL;DEFAULT:0"`
+	FirstBuy bool      `gorm:"NOT NULL;DEFAULT:false"`
+	Date     time.Time `gorm:"INDEX;NOT NULL"`
+}
+
+// KnownPattern is a pattern that an User knows his island had in a given week in a Group.
+// This allows to use patterns tracked outside the bot as the previous week for the forecasts.
+// Known patterns aren't audited, ChangedAt is set by the database so undo can tell if they changed after a change.
+type KnownPattern struct {
+	ID        uint64      `gorm:"PRIMARY_KEY;AUTO_INCREMENT;NOT NULL"`
+	GroupID   int64       `gorm:"INDEX;NOT NULL"`
+	Group     Group       `gorm:"FOREIGNKEY:GroupID"`
+	UserID    int64       `gorm:"INDEX;NOT NULL"`
+	User      User        `gorm:"FOREIGNKEY:UserID"`
+	Pattern   PatternType `gorm:"NOT NULL;DEFAULT:0"`
+	Date      time.Time   `gorm:"INDEX;NOT NULL"`
+	ChangedAt time.Time   `gorm:"NOT NULL;DEFAULT:now()"`
+}
+
+// AuditRecord is the kind of record an AuditEntry refers to
+type AuditRecord string
+
+const (
+	// AuditPrice is a Price record
+	AuditPrice AuditRecord = "price"
+	// AuditOwned is an Owned record
+	AuditOwned AuditRecord = "owned"
+	// AuditIslandPrice is an IslandPrice record
+	AuditIslandPrice AuditRecord = "island_price"
+)
+
+// AuditValues are the values of a record at some point, Exists is false if there was no record
+type AuditValues struct {
+	Exists   bool   `gorm:"NOT NULL;DEFAULT:false"`
+	Units    uint32 `gorm:"NOT NULL;DEFAULT:0"`
+	Bells    uint32 `gorm:"NOT NULL;DEFAULT:0"`
+	FirstBuy bool   `gorm:"NOT NULL;DEFAULT:false"`
+}
+
+// AuditEntry is a change made by an author to a record of an User in a Group.
+// All the entries of a change share ChangedAt, which is set by the database to the transaction time.
+type AuditEntry struct {
+	ID        uint64      `gorm:"PRIMARY_KEY;AUTO_INCREMENT;NOT NULL"`
+	GroupID   int64       `gorm:"INDEX;NOT NULL"`
+	Group     Group       `gorm:"FOREIGNKEY:GroupID"`
+	UserID    int64       `gorm:"INDEX;NOT NULL"`
+	User      User        `gorm:"FOREIGNKEY:UserID"`
+	AuthorID  int64       `gorm:"INDEX;NOT NULL"`
+	Author    User        `gorm:"FOREIGNKEY:AuthorID"`
+	Record    AuditRecord `gorm:"NOT NULL"`
+	Date      time.Time   `gorm:"NOT NULL"`
+	Old       AuditValues `gorm:"EMBEDDED;EMBEDDED_PREFIX:old_"`
+	New       AuditValues `gorm:"EMBEDDED;EMBEDDED_PREFIX:new_"`
+	Undone    bool        `gorm:"NOT NULL;DEFAULT:false"`
+	ChangedAt time.Time   `gorm:"INDEX;NOT NULL;DEFAULT:now()"`
 }
 
 // SetupDB runs database migrations
@@ -120,6 +255,9 @@ func (d *Database) SetupDB() {
 		&Price{},
 		&Owned{},
 		&IslandPrice{},
+		&KnownPattern{},
+		&Sale{},
+		&AuditEntry{},
 	)
 
 	// Add the FKs
@@ -134,4 +272,16 @@ func (d *Database) SetupDB() {
 	islandPriceModel := d.DB.Model(&IslandPrice{})
 	islandPriceModel.AddForeignKey("group_id", "groups(id)", "CASCADE", "CASCADE")
 	islandPriceModel.AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE")
+
+	knownPatternModel := d.DB.Model(&KnownPattern{})
+	knownPatternModel.AddForeignKey("group_id", "groups(id)", "CASCADE", "CASCADE")
+	knownPatternModel.AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE")
+
+	saleModel := d.DB.Model(&Sale{})
+	saleModel.AddForeignKey("owned_id", "owneds(id)", "CASCADE", "CASCADE")
+
+	auditEntryModel := d.DB.Model(&AuditEntry{})
+	auditEntryModel.AddForeignKey("group_id", "groups(id)", "CASCADE", "CASCADE")
+	auditEntryModel.AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE")
+	auditEntryModel.AddForeignKey("author_id", "users(id)", "CASCADE", "CASCADE")
 }
@@ -17,6 +17,8 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	tb "gopkg.in/tucnak/telebot.v3"
@@ -35,8 +37,41 @@ var (
 
 	// ErrBuyDay is returned when an user tries to set a sell price on a buy day
 	ErrBuyDay = errors.New("date is buy day, can't store a sell price")
+
+	// ErrNoOwned is returned when an user tries to sell turnips without having bought them this week
+	ErrNoOwned = errors.New("no turnips bought this week")
+
+	// ErrNotEnoughUnits is returned when an user tries to sell more turnips than the remaining ones
+	ErrNotEnoughUnits = errors.New("not enough turnips to sell")
+
+	// ErrNotThisWeek is returned when an user input date doesn't belong to the current week
+	ErrNotThisWeek = errors.New("date doesn't belong to this week")
+
+	// ErrNoRecord is returned when an user tries to remove a record that doesn't exist
+	ErrNoRecord = errors.New("record not found")
+
+	// ErrHasSales is returned when an user tries to remove turnips that have sales
+	ErrHasSales = errors.New("turnips have sales")
+
+	// ErrNoChanges is returned when an user tries to undo but there are no changes left
+	ErrNoChanges = errors.New("no changes to undo")
+
+	// ErrChangedSince is returned when an user tries to undo a change to a record that was changed afterwards
+	ErrChangedSince = errors.New("record changed since")
+	// ErrUnauditedSince is returned when an user tries to undo a change made before a sale or a known pattern
+	ErrUnauditedSince = errors.New("unaudited change since")
 )
 
+// FutureHalfDayError is returned when an user tries to save a price of a half-day that hasn't started yet
+type FutureHalfDayError struct {
+	Date time.Time
+}
+
+// Error returns the error message
+func (e *FutureHalfDayError) Error() string {
+	return fmt.Sprintf("half-day %s hasn't started yet", e.Date.Format(timeFormatAMPM))
+}
+
 /********************
  Models: Group, User
 *********************/
@@ -128,6 +163,143 @@ func (d *Database) GetUser(u *tb.User) (*User, error) {
 	return user, err
 }
 
+// GetUserByID returns the user entity with the given id without creating it, nil if it doesn't exist
+func (d *Database) GetUserByID(id int64) (*User, error) {
+	user := &User{}
+
+	err := d.DB.Where("id = ?", id).First(user).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+
+		log.Error().Str("module", "database").Err(err).Msg("error getting user by id")
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ChangeUserAlerts changes if the user wants to be mentioned in the high price alerts
+func (d *Database) ChangeUserAlerts(u *tb.User, enabled bool) error {
+	// Get user
+	user, err := d.GetUser(u)
+	if err != nil {
+		return err
+	}
+
+	// Update NoAlerts value
+	user.NoAlerts = !enabled
+
+	err = d.DB.Save(user).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error saving user alerts")
+	}
+
+	return err
+}
+
+// ChangeUserLang changes the language override of the user, empty for the group one
+func (d *Database) ChangeUserLang(u *tb.User, lang string) error {
+	// Get user
+	user, err := d.GetUser(u)
+	if err != nil {
+		return err
+	}
+
+	// Update Lang value
+	user.Lang = lang
+
+	err = d.DB.Save(user).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error saving user lang")
+	}
+
+	return err
+}
+
+// GetGroupUsersByUsername returns the users with records in the group that have the given username, it could be
+// outdated for some of them
+func (d *Database) GetGroupUsersByUsername(c *tb.Chat, username string) ([]*User, error) {
+	users := []*User{}
+
+	err := d.DB.Where("LOWER(username) = LOWER(?)", username).Where(
+		"id IN (?) OR id IN (?) OR id IN (?)",
+		d.DB.Model(&Price{}).Select("user_id").Where("group_id = ?", c.ID).SubQuery(),
+		d.DB.Model(&Owned{}).Select("user_id").Where("group_id = ?", c.ID).SubQuery(),
+		d.DB.Model(&IslandPrice{}).Select("user_id").Where("group_id = ?", c.ID).SubQuery(),
+	).Find(&users).Error
+
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error getting group users by username")
+	}
+
+	return users, err
+}
+
+// GetUserGroups returns the groups where the user recorded a price or owned turnips
+func (d *Database) GetUserGroups(u *tb.User) ([]*Group, error) {
+	groups := []*Group{}
+
+	err := d.DB.Where(
+		"id IN (?) OR id IN (?)",
+		d.DB.Model(&Price{}).Select("group_id").Where("user_id = ?", u.ID).SubQuery(),
+		d.DB.Model(&Owned{}).Select("group_id").Where("user_id = ?", u.ID).SubQuery(),
+	).Order("title ASC").Find(&groups).Error
+
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error getting user groups")
+	}
+
+	return groups, err
+}
+
+// GetUserDashboardGroup returns the group selected by the user for the private dashboard, nil if none
+func (d *Database) GetUserDashboardGroup(u *tb.User) (*Group, error) {
+	// Get user
+	user, err := d.GetUser(u)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DashboardGroupID == 0 {
+		return nil, nil
+	}
+
+	group := &Group{}
+
+	err = d.DB.Where(&Group{ID: user.DashboardGroupID}).First(&group).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+
+		log.Error().Str("module", "database").Err(err).Msg("error getting user dashboard group")
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// ChangeUserDashboardGroup changes the group selected by the user for the private dashboard
+func (d *Database) ChangeUserDashboardGroup(u *tb.User, g *Group) error {
+	// Get user
+	user, err := d.GetUser(u)
+	if err != nil {
+		return err
+	}
+
+	// Update DashboardGroupID value
+	user.DashboardGroupID = g.ID
+
+	err = d.DB.Save(user).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error saving user dashboard group")
+	}
+
+	return err
+}
+
 // GetUserAndGroup returns the user and group database entities given the user and chat telegram entities
 func (d *Database) GetUserAndGroup(u *tb.User, c *tb.Chat) (*User, *Group, error) {
 	// Get user
@@ -180,6 +352,140 @@ func (d *Database) ChangeGroupDeleteSeconds(c *tb.Chat, seconds uint8) error {
 	return err
 }
 
+// ChangeGroupAlerts changes the group high price alerts settings
+func (d *Database) ChangeGroupAlerts(c *tb.Chat, bells uint32, margin uint16) error {
+	// Get group
+	group, err := d.GetGroup(c)
+	if err != nil {
+		return err
+	}
+
+	// Update AlertBells and AlertMargin values
+	group.AlertBells = bells
+	group.AlertMargin = margin
+
+	err = d.DB.Save(group).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error saving group alerts")
+	}
+
+	return err
+}
+
+// ChangeGroupSellReminders changes the group sell reminders setting
+func (d *Database) ChangeGroupSellReminders(c *tb.Chat, times []string) error {
+	// Get group
+	group, err := d.GetGroup(c)
+	if err != nil {
+		return err
+	}
+
+	// Update SellReminders value
+	group.SellReminders = strings.Join(times, ",")
+
+	err = d.DB.Save(group).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error saving group sell reminders")
+	}
+
+	return err
+}
+
+// ChangeGroupBuyReminder changes the group buy reminder setting
+func (d *Database) ChangeGroupBuyReminder(c *tb.Chat, clock string) error {
+	// Get group
+	group, err := d.GetGroup(c)
+	if err != nil {
+		return err
+	}
+
+	// Update BuyReminder value
+	group.BuyReminder = clock
+
+	err = d.DB.Save(group).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error saving group buy reminder")
+	}
+
+	return err
+}
+
+// ChangeGroupLang changes the language of the group, empty for the default one
+func (d *Database) ChangeGroupLang(c *tb.Chat, lang string) error {
+	// Get group
+	group, err := d.GetGroup(c)
+	if err != nil {
+		return err
+	}
+
+	// Update Lang value
+	group.Lang = lang
+
+	err = d.DB.Save(group).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error saving group lang")
+	}
+
+	return err
+}
+
+// GetGroups returns all the groups
+func (d *Database) GetGroups() ([]*Group, error) {
+	groups := []*Group{}
+
+	err := d.DB.Find(&groups).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error getting groups")
+	}
+
+	return groups, err
+}
+
+// GetGroupByID returns a group given its id, nil if it doesn't exist
+func (d *Database) GetGroupByID(id int64) (*Group, error) {
+	group := &Group{}
+
+	err := d.DB.Where("id = ?", id).First(group).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+
+		log.Error().Str("module", "database").Err(err).Msg("error getting group by id")
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// GetGroupWeekUsers returns the users that recorded a price, an owned or an island price in the group the week the time belongs to
+func (d *Database) GetGroupWeekUsers(g *Group, t time.Time) ([]*User, error) {
+	// Get now config with group timezone
+	nowCfg, err := g.NowConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	bowDate := nowCfg.With(t.In(nowCfg.TimeLocation)).BeginningOfWeek()
+	eowDate := nowCfg.With(bowDate).EndOfWeek()
+
+	// Query users with any record this week
+	users := []*User{}
+
+	err = d.DB.Where(
+		"id IN (?) OR id IN (?) OR id IN (?)",
+		d.DB.Model(&Price{}).Select("user_id").Where("group_id = ? AND date >= ? AND date <= ?", g.ID, bowDate, eowDate).SubQuery(),
+		d.DB.Model(&Owned{}).Select("user_id").Where("group_id = ? AND date = ?", g.ID, bowDate).SubQuery(),
+		d.DB.Model(&IslandPrice{}).Select("user_id").Where("group_id = ? AND date = ?", g.ID, bowDate).SubQuery(),
+	).Find(&users).Error
+
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error getting group week users")
+	}
+
+	return users, err
+}
+
 // ChangeGroupTZ changes the group delete seconds setting
 func (d *Database) ChangeGroupTZ(c *tb.Chat, tz string) (string, error) {
 	// Get group
@@ -241,38 +547,33 @@ func (d *Database) getUserIslandPrice(u *User, g *Group, t time.Time) (*IslandPr
 }
 
 // saveUserIslandPrice sets the buy price in an user island
-func (d *Database) saveUserIslandPrice(u *User, g *Group, bells uint32) (bool, uint32, error) {
+func (d *Database) saveUserIslandPrice(author *User, u *User, g *Group, bells uint32, firstBuy bool) (bool, uint32, error) {
 	// Get now config with group timezone
 	nowCfg, err := g.NowConfig()
 	if err != nil {
 		return false, 0, err
 	}
 
-	// Get previous island price if exists
-	islandPrice, err := d.getUserIslandPrice(u, g, time.Now())
+	date := nowCfg.With(time.Now().In(nowCfg.TimeLocation)).BeginningOfWeek()
+
+	old, _, err := d.saveUserRecord(author, u, g, AuditIslandPrice, date, AuditValues{Exists: true, Bells: bells, FirstBuy: firstBuy})
 	if err != nil {
-		return false, 0, err
+		log.Error().Str("module", "database").Err(err).Bool("new", !old.Exists).Msg("error saving island price")
 	}
 
-	new := db.DB.NewRecord(islandPrice)
-	oldBells := islandPrice.Bells
-
-	islandPrice.UserID = u.ID
-	islandPrice.GroupID = g.ID
-	islandPrice.Bells = bells
-	islandPrice.Date = nowCfg.With(time.Now().In(nowCfg.TimeLocation)).BeginningOfWeek()
+	return !old.Exists, old.Bells, err
+}
 
-	if new {
-		err = d.DB.Create(&islandPrice).Error
-	} else {
-		err = d.DB.Save(&islandPrice).Error
-	}
+// getGroupIslandPrices gets all the island prices recorded in a group
+func (d *Database) getGroupIslandPrices(g *Group) ([]*IslandPrice, error) {
+	islandPrices := []*IslandPrice{}
 
+	err := d.DB.Set("gorm:auto_preload", true).Where("group_id = ?", g.ID).Order("date ASC, user_id ASC").Find(&islandPrices).Error
 	if err != nil {
-		log.Error().Str("module", "database").Err(err).Bool("new", new).Msg("error saving island price")
+		log.Error().Str("module", "database").Err(err).Msg("error getting group island prices")
 	}
 
-	return new, oldBells, err
+	return islandPrices, err
 }
 
 /* Public methods */
@@ -288,6 +589,50 @@ func (d *Database) GetUserIslandPrice(u *tb.User, c *tb.Chat) (*IslandPrice, err
 	return d.getUserIslandPrice(user, group, time.Now())
 }
 
+// SaveMemberIslandPrice sets the island price of a group member this week on behalf of an author
+func (d *Database) SaveMemberIslandPrice(a *tb.User, c *tb.Chat, member *User, bells uint32) (bool, uint32, error) {
+	// Get author and group
+	author, group, err := d.GetUserAndGroup(a, c)
+	if err != nil {
+		return false, 0, err
+	}
+
+	// Keep the first buy flag as the member set it
+	islandPrice, err := d.getUserIslandPrice(member, group, time.Now())
+	if err != nil {
+		return false, 0, err
+	}
+
+	return d.saveUserIslandPrice(author, member, group, bells, islandPrice.FirstBuy)
+}
+
+// GetGroupWeekIslandPrices gets the island prices of all the users in a group this week
+func (d *Database) GetGroupWeekIslandPrices(c *tb.Chat) ([]*IslandPrice, error) {
+	// Get group
+	group, err := d.GetGroup(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get now config with group timezone
+	nowCfg, err := group.NowConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	bowDate := nowCfg.With(time.Now().In(nowCfg.TimeLocation)).BeginningOfWeek()
+
+	// Query current group island prices
+	islandPrices := []*IslandPrice{}
+
+	err = d.DB.Set("gorm:auto_preload", true).Where("group_id = ? AND date = ? AND bells > 0", group.ID, bowDate).Find(&islandPrices).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error getting group island prices")
+	}
+
+	return islandPrices, err
+}
+
 // GetUserIslandPriceByDate gets the buy price in an user island
 func (d *Database) GetUserIslandPriceByDate(u *tb.User, c *tb.Chat, t time.Time) (*IslandPrice, error) {
 	// Get user and group
@@ -299,130 +644,344 @@ func (d *Database) GetUserIslandPriceByDate(u *tb.User, c *tb.Chat, t time.Time)
 	return d.getUserIslandPrice(user, group, t)
 }
 
-// SaveUserIslandPrice sets the buy price in an user island
-func (d *Database) SaveUserIslandPrice(u *tb.User, c *tb.Chat, bells uint32) (bool, uint32, error) {
+// SaveUserIslandPrice sets the buy price in an user island and if it is the first week buying in it
+func (d *Database) SaveUserIslandPrice(u *tb.User, c *tb.Chat, bells uint32, firstBuy bool) (bool, uint32, error) {
 	// Get user and group
 	user, group, err := d.GetUserAndGroup(u, c)
 	if err != nil {
 		return false, 0, err
 	}
 
-	return d.saveUserIslandPrice(user, group, bells)
+	return d.saveUserIslandPrice(user, user, group, bells, firstBuy)
 }
 
-/*************
- Model: Owned
-**************/
+/********************
+ Model: KnownPattern
+*********************/
 
 /* Private methods */
 
-// getUserWeekOwned returns owned turnips by the user this week
-func (d *Database) getUserWeekOwned(u *User, g *Group) (*Owned, error) {
+// getUserKnownPattern returns the known pattern of the user island in the week the time belongs to
+func (d *Database) getUserKnownPattern(u *User, g *Group, t time.Time) (*KnownPattern, error) {
 	// Get now config with group timezone
 	nowCfg, err := g.NowConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	bowDate := nowCfg.With(time.Now().In(nowCfg.TimeLocation)).BeginningOfWeek()
+	bowDate := nowCfg.With(t.In(nowCfg.TimeLocation)).BeginningOfWeek()
 
-	// Get this week owned
-	owned := &Owned{}
+	// Get the week known pattern
+	knownPattern := &KnownPattern{}
 
 	err = d.DB.Where("user_id = ? AND group_id = ? AND date = ?",
 		u.ID,
 		g.ID,
 		bowDate,
-	).First(&owned).Error
+	).First(&knownPattern).Error
 
 	if err != nil && !gorm.IsRecordNotFoundError(err) {
-		log.Error().Str("module", "database").Err(err).Msg("error getting user owned")
+		log.Error().Str("module", "database").Err(err).Msg("error getting known pattern")
 		return nil, err
 	}
 
-	return owned, nil
+	return knownPattern, nil
 }
 
-/* Public methods */
-
-// GetGroupWeekOwned returns owned turnips by all the users in a group this week
-func (d *Database) GetGroupWeekOwned(c *tb.Chat) ([]*Owned, error) {
-	// Get group
-	group, err := d.GetGroup(c)
+// saveUserKnownPattern sets the known pattern of the user island in the week the time belongs to
+func (d *Database) saveUserKnownPattern(u *User, g *Group, pattern PatternType, t time.Time) (bool, PatternType, error) {
+	// Get now config with group timezone
+	nowCfg, err := g.NowConfig()
 	if err != nil {
-		return nil, err
+		return false, 0, err
 	}
 
-	// Get now config with group timezone
-	nowCfg, err := group.NowConfig()
+	// Get previous known pattern if exists
+	knownPattern, err := d.getUserKnownPattern(u, g, t)
 	if err != nil {
-		return nil, err
+		return false, 0, err
 	}
 
-	bowDate := nowCfg.With(time.Now().In(nowCfg.TimeLocation)).BeginningOfWeek()
+	new := d.DB.NewRecord(knownPattern)
+	oldPattern := knownPattern.Pattern
 
-	// Query current group owneds
-	owneds := []*Owned{}
+	knownPattern.UserID = u.ID
+	knownPattern.GroupID = g.ID
+	knownPattern.Pattern = pattern
+	knownPattern.Date = nowCfg.With(t.In(nowCfg.TimeLocation)).BeginningOfWeek()
+
+	if new {
+		err = d.DB.Create(&knownPattern).Error
+	} else {
+		err = d.DB.Model(knownPattern).Updates(map[string]interface{}{
+			"pattern":    knownPattern.Pattern,
+			"changed_at": gorm.Expr("now()"),
+		}).Error
+	}
 
-	err = d.DB.Set("gorm:auto_preload", true).Where("group_id = ? AND date = ?", group.ID, bowDate).Order("units DESC").Find(&owneds).Error
 	if err != nil {
-		log.Error().Str("module", "database").Err(err).Msg("error getting group owneds")
+		log.Error().Str("module", "database").Err(err).Bool("new", new).Msg("error saving known pattern")
 	}
 
-	return owneds, err
+	return new, oldPattern, err
 }
 
-// GetUserWeekOwned returns owned turnips by the user this week
-func (d *Database) GetUserWeekOwned(u *tb.User, c *tb.Chat) (*Owned, error) {
+/* Public methods */
+
+// GetUserKnownPatternByDate gets the known pattern of the user island in the week the time belongs to, nil if unknown
+func (d *Database) GetUserKnownPatternByDate(u *tb.User, c *tb.Chat, t time.Time) (*KnownPattern, error) {
+	// Get user and group
 	user, group, err := d.GetUserAndGroup(u, c)
 	if err != nil {
 		return nil, err
 	}
 
-	return d.getUserWeekOwned(user, group)
+	knownPattern, err := d.getUserKnownPattern(user, group, t)
+	if err != nil || d.DB.NewRecord(knownPattern) {
+		return nil, err
+	}
+
+	return knownPattern, nil
 }
 
-// SaveThisWeekOwned sets owned turnips by the user this week
-func (d *Database) SaveThisWeekOwned(u *tb.User, c *tb.Chat, units uint32, bells uint32) (bool, uint32, uint32, error) {
+// SaveUserLastWeekPattern sets the known pattern of the user island last week
+func (d *Database) SaveUserLastWeekPattern(u *tb.User, c *tb.Chat, pattern PatternType) (bool, PatternType, error) {
 	// Get user and group
 	user, group, err := d.GetUserAndGroup(u, c)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return d.saveUserKnownPattern(user, group, pattern, time.Now().AddDate(0, 0, -7))
+}
+
+/*************
+ Model: Owned
+**************/
+
+/* Private methods */
+
+// getUserWeekOwned returns owned turnips by the user this week
+func (d *Database) getUserWeekOwned(u *User, g *Group) (*Owned, error) {
+	// Get now config with group timezone
+	nowCfg, err := g.NowConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	bowDate := nowCfg.With(time.Now().In(nowCfg.TimeLocation)).BeginningOfWeek()
+
+	// Get this week owned
+	owned := &Owned{}
+
+	err = d.DB.Preload("Sales").Where("user_id = ? AND group_id = ? AND date = ?",
+		u.ID,
+		g.ID,
+		bowDate,
+	).First(&owned).Error
+
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		log.Error().Str("module", "database").Err(err).Msg("error getting user owned")
+		return nil, err
+	}
+
+	return owned, nil
+}
+
+// getGroupOwneds returns owned turnips by all the users in a group between two dates, end excluded
+func (d *Database) getGroupOwneds(g *Group, from time.Time, to time.Time) ([]*Owned, error) {
+	owneds := []*Owned{}
+
+	err := d.DB.Set("gorm:auto_preload", true).Where("group_id = ? AND date >= ? AND date < ?", g.ID, from, to).Order("date ASC").Find(&owneds).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error getting group owneds")
+	}
+
+	return owneds, err
+}
+
+// getGroupAllOwneds gets all the turnips owned in a group
+func (d *Database) getGroupAllOwneds(g *Group) ([]*Owned, error) {
+	owneds := []*Owned{}
+
+	err := d.DB.Set("gorm:auto_preload", true).Where("group_id = ?", g.ID).Order("date ASC, user_id ASC").Find(&owneds).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error getting all group owneds")
+	}
+
+	return owneds, err
+}
+
+// saveUserWeekOwned sets how many turnips an User owns in a Group this week and the price paid
+func (d *Database) saveUserWeekOwned(author *User, u *User, g *Group, units uint32, bells uint32) (bool, uint32, uint32, error) {
+	// Get now config with group timezone
+	nowCfg, err := g.NowConfig()
 	if err != nil {
 		return false, 0, 0, err
 	}
 
+	date := nowCfg.With(time.Now().In(nowCfg.TimeLocation)).BeginningOfWeek()
+
+	old, _, err := d.saveUserRecord(author, u, g, AuditOwned, date, AuditValues{Exists: true, Units: units, Bells: bells})
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Bool("new", !old.Exists).Msg("error saving user owned")
+	}
+
+	return !old.Exists, old.Units, old.Bells, err
+}
+
+/* Public methods */
+
+// GetGroupWeekOwned returns owned turnips by all the users in a group this week
+func (d *Database) GetGroupWeekOwned(c *tb.Chat) ([]*Owned, error) {
+	// Get group
+	group, err := d.GetGroup(c)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get now config with group timezone
 	nowCfg, err := group.NowConfig()
 	if err != nil {
-		return false, 0, 0, err
+		return nil, err
 	}
 
-	// Get current week owneds if exists
-	owned, err := db.getUserWeekOwned(user, group)
+	bowDate := nowCfg.With(time.Now().In(nowCfg.TimeLocation)).BeginningOfWeek()
+
+	// Query current group owneds
+	owneds := []*Owned{}
+
+	err = d.DB.Set("gorm:auto_preload", true).Where("group_id = ? AND date = ?", group.ID, bowDate).Order("units DESC").Find(&owneds).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error getting group owneds")
+	}
+
+	return owneds, err
+}
+
+// GetUserWeekOwned returns owned turnips by the user this week
+func (d *Database) GetUserWeekOwned(u *tb.User, c *tb.Chat) (*Owned, error) {
+	user, group, err := d.GetUserAndGroup(u, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.getUserWeekOwned(user, group)
+}
+
+// SaveMemberWeekOwned sets owned turnips by a group member this week on behalf of an author
+func (d *Database) SaveMemberWeekOwned(a *tb.User, c *tb.Chat, member *User, units uint32, bells uint32) (bool, uint32, uint32, error) {
+	// Get author and group
+	author, group, err := d.GetUserAndGroup(a, c)
 	if err != nil {
 		return false, 0, 0, err
 	}
 
-	new := db.DB.NewRecord(owned)
-	oldUnits := owned.Units
-	oldBells := owned.Bells
+	return d.saveUserWeekOwned(author, member, group, units, bells)
+}
+
+// SaveThisWeekBuy sets owned turnips and island price of the user this week at once, so they are undone together
+func (d *Database) SaveThisWeekBuy(u *tb.User, c *tb.Chat, units, bells, islandPrice uint32, firstBuy bool) (bool, uint32, uint32, bool, uint32, error) {
+	// Get user and group
+	user, group, err := d.GetUserAndGroup(u, c)
+	if err != nil {
+		return false, 0, 0, false, 0, err
+	}
+
+	var newO, newIP bool
+	var oldUnits, oldBells, oldIslandPrice uint32
+	err = d.Transaction(func(tx *Database) error {
+		var errs error
+		newO, oldUnits, oldBells, errs = tx.saveUserWeekOwned(user, user, group, units, bells)
+		if errs != nil {
+			return errs
+		}
 
-	owned.UserID = user.ID
-	owned.GroupID = group.ID
-	owned.Units = units
-	owned.Bells = bells
-	owned.Date = nowCfg.With(time.Now().In(nowCfg.TimeLocation)).BeginningOfWeek()
+		newIP, oldIslandPrice, errs = tx.saveUserIslandPrice(user, user, group, islandPrice, firstBuy)
+		return errs
+	})
 
-	if new {
-		err = d.DB.Create(&owned).Error
+	return newO, oldUnits, oldBells, newIP, oldIslandPrice, err
+}
+
+/************
+ Model: Sale
+*************/
+
+/* Public methods */
+
+// SaveThisWeekSale records turnips sold by the user this week, the date is the current half day if empty
+func (d *Database) SaveThisWeekSale(u *tb.User, c *tb.Chat, units uint32, bells uint32, dateStr string) (*Owned, string, error) {
+	// Get user and group
+	user, group, err := d.GetUserAndGroup(u, c)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Get now config with group timezone
+	nowCfg, err := group.NowConfig()
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Get the sale date, by default the current half day
+	date := time.Now().In(nowCfg.TimeLocation)
+
+	if dateStr != "" {
+		date, err = parseHalfDay(dateStr, nowCfg, date)
+		if err != nil {
+			return nil, "", ErrDateParse
+		}
+	}
+
+	amDate := nowCfg.With(date).BeginningOfDay()
+	pmDate := amDate.Add(time.Hour * 12)
+
+	if date.Before(pmDate) {
+		date = amDate
 	} else {
-		err = d.DB.Save(&owned).Error
+		date = pmDate
+	}
+
+	if date.Weekday() == turnipSellDay {
+		return nil, date.Format(timeFormatAMPM), ErrBuyDay
+	}
+
+	// Get current week owned
+	owned, err := d.getUserWeekOwned(user, group)
+	if err != nil {
+		return nil, date.Format(timeFormatAMPM), err
+	}
+
+	if d.DB.NewRecord(owned) || owned.Units == 0 {
+		return nil, date.Format(timeFormatAMPM), ErrNoOwned
+	}
+
+	if !nowCfg.With(date).BeginningOfWeek().Equal(owned.Date) {
+		return nil, date.Format(timeFormatAMPM), ErrNotThisWeek
+	}
+
+	if units > owned.RemainingUnits() {
+		return owned, date.Format(timeFormatAMPM), ErrNotEnoughUnits
 	}
 
+	// Save sale
+	sale := Sale{
+		OwnedID: owned.ID,
+		Units:   units,
+		Bells:   bells,
+		Date:    date,
+	}
+
+	err = d.DB.Create(&sale).Error
 	if err != nil {
-		log.Error().Str("module", "database").Err(err).Bool("new", new).Msg("error saving user owned")
+		log.Error().Str("module", "database").Err(err).Msg("error saving user sale")
+		return nil, date.Format(timeFormatAMPM), err
 	}
 
-	return new, oldUnits, oldBells, err
+	owned.Sales = append(owned.Sales, sale)
+
+	return owned, date.Format(timeFormatAMPM), nil
 }
 
 /*************
@@ -468,52 +1027,62 @@ func (d *Database) getUserPrice(u *User, g *Group, t time.Time) (*Price, error)
 	return price, nil
 }
 
-// saveUserPrice sets sell price at Nook's Cranny at a given time
-func (d *Database) saveUserPrice(u *User, g *Group, bells uint32, t time.Time) (bool, uint32, string, error) {
-	// If is sell day then there is no market
-	if t.Weekday() == turnipSellDay {
-		return false, 0, t.Format(timeFormatAMPM), ErrBuyDay
-	}
-
-	// Save price
-	price, err := d.getUserPrice(u, g, t)
+// getUserWeekPrices gets user prices recorded in the week the time belongs to
+func (d *Database) getUserWeekPrices(u *User, g *Group, t time.Time) ([]*Price, error) {
+	// Get now config with group timezone
+	nowCfg, err := g.NowConfig()
 	if err != nil {
-		return false, 0, t.Format(timeFormatAMPM), err
+		return nil, err
 	}
 
-	new := db.DB.NewRecord(price)
-	oldBells := price.Bells
+	// Get week start and end dates
+	bowDate := nowCfg.With(t.In(nowCfg.TimeLocation)).BeginningOfWeek()
+	eowDate := nowCfg.With(bowDate).EndOfWeek()
 
-	price.UserID = u.ID
-	price.GroupID = g.ID
-	price.Bells = bells
-	price.Date = t
+	// Query user week prices
+	prices := []*Price{}
 
-	if new {
-		err = d.DB.Create(&price).Error
-	} else {
-		err = d.DB.Save(&price).Error
-	}
+	err = d.DB.Where(
+		"user_id = ? AND group_id = ? AND date >= ? AND date <= ?",
+		u.ID,
+		g.ID,
+		bowDate,
+		eowDate,
+	).Order("date ASC").Find(&prices).Error
 
 	if err != nil {
-		log.Error().Str("module", "database").Err(err).Bool("new", new).Msg("error saving user price")
+		log.Error().Str("module", "database").Err(err).Msg("error getting user prices")
 	}
 
-	return new, oldBells, t.Format(timeFormatAMPM), nil
+	return prices, err
 }
 
-/* Public methods */
+// saveUserPrice sets sell price at Nook's Cranny at a given time
+func (d *Database) saveUserPrice(author *User, u *User, g *Group, bells uint32, t time.Time) (bool, uint32, string, error) {
+	// If is sell day then there is no market
+	if t.Weekday() == turnipSellDay {
+		return false, 0, t.Format(timeFormatAMPM), ErrBuyDay
+	}
 
-// GetGroupCurrentPrices gets current sell price at Nook's Cranny
-func (d *Database) GetGroupCurrentPrices(c *tb.Chat) ([]*Price, string, error) {
-	// Get group
-	group, err := d.GetGroup(c)
+	// Get now config with group timezone
+	nowCfg, err := g.NowConfig()
 	if err != nil {
-		return nil, "", err
+		return false, 0, t.Format(timeFormatAMPM), err
+	}
+
+	// Save price
+	old, _, err := d.saveUserRecord(author, u, g, AuditPrice, currentHalfDay(nowCfg, t), AuditValues{Exists: true, Bells: bells})
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Bool("new", !old.Exists).Msg("error saving user price")
 	}
 
+	return !old.Exists, old.Bells, t.Format(timeFormatAMPM), err
+}
+
+// getGroupCurrentPrices gets current sell price at Nook's Cranny
+func (d *Database) getGroupCurrentPrices(g *Group) ([]*Price, string, error) {
 	// Get now config with group timezone
-	nowCfg, err := group.NowConfig()
+	nowCfg, err := g.NowConfig()
 	if err != nil {
 		return nil, "", err
 	}
@@ -533,7 +1102,7 @@ func (d *Database) GetGroupCurrentPrices(c *tb.Chat) ([]*Price, string, error) {
 	// Query current group prices
 	prices := []*Price{}
 
-	err = d.DB.Set("gorm:auto_preload", true).Where("group_id = ? AND date = ?", group.ID, reqDate).Order("bells DESC").Find(&prices).Error
+	err = d.DB.Set("gorm:auto_preload", true).Where("group_id = ? AND date = ?", g.ID, reqDate).Order("bells DESC").Find(&prices).Error
 	if err != nil {
 		log.Error().Str("module", "database").Err(err).Msg("error getting group prices")
 	}
@@ -541,40 +1110,57 @@ func (d *Database) GetGroupCurrentPrices(c *tb.Chat) ([]*Price, string, error) {
 	return prices, reqDate.Format(timeFormatAMPM), err
 }
 
-// GetUserWeekPrices gets user prices recorded in the week the time belongs to
-func (d *Database) GetUserWeekPrices(u *tb.User, c *tb.Chat, t time.Time) ([]*Price, error) {
-	// Get user and group
-	user, group, err := d.GetUserAndGroup(u, c)
+// getGroupBestPrice gets the highest sell price recorded in a group between two dates, end excluded, nil if there is none
+func (d *Database) getGroupBestPrice(g *Group, from time.Time, to time.Time) (*Price, error) {
+	price := &Price{}
+
+	err := d.DB.Set("gorm:auto_preload", true).Where("group_id = ? AND date >= ? AND date < ?", g.ID, from, to).Order("bells DESC").First(price).Error
 	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+
+		log.Error().Str("module", "database").Err(err).Msg("error getting group best price")
 		return nil, err
 	}
 
-	// Get now config with group timezone
-	nowCfg, err := group.NowConfig()
+	return price, nil
+}
+
+// getGroupPrices gets all the sell prices recorded in a group
+func (d *Database) getGroupPrices(g *Group) ([]*Price, error) {
+	prices := []*Price{}
+
+	err := d.DB.Set("gorm:auto_preload", true).Where("group_id = ?", g.ID).Order("date ASC, user_id ASC").Find(&prices).Error
 	if err != nil {
-		return nil, err
+		log.Error().Str("module", "database").Err(err).Msg("error getting group prices")
 	}
 
-	// Get week start and end dates
-	bowDate := nowCfg.With(t.In(nowCfg.TimeLocation)).BeginningOfWeek()
-	eowDate := nowCfg.With(bowDate).EndOfWeek()
+	return prices, err
+}
 
-	// Query current group prices
-	prices := []*Price{}
+/* Public methods */
 
-	err = d.DB.Where(
-		"user_id = ? AND group_id = ? AND date >= ? AND date <= ?",
-		user.ID,
-		group.ID,
-		bowDate,
-		eowDate,
-	).Order("date ASC").Find(&prices).Error
+// GetGroupCurrentPrices gets current sell price at Nook's Cranny
+func (d *Database) GetGroupCurrentPrices(c *tb.Chat) ([]*Price, string, error) {
+	// Get group
+	group, err := d.GetGroup(c)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return d.getGroupCurrentPrices(group)
+}
 
+// GetUserWeekPrices gets user prices recorded in the week the time belongs to
+func (d *Database) GetUserWeekPrices(u *tb.User, c *tb.Chat, t time.Time) ([]*Price, error) {
+	// Get user and group
+	user, group, err := d.GetUserAndGroup(u, c)
 	if err != nil {
-		log.Error().Str("module", "database").Err(err).Msg("error getting user prices")
+		return nil, err
 	}
 
-	return prices, err
+	return d.getUserWeekPrices(user, group, t)
 }
 
 // SaveUserPrice sets sell price at Nook's Cranny at a given time
@@ -592,13 +1178,80 @@ func (d *Database) SaveUserPrice(u *tb.User, c *tb.Chat, bells uint32, dateStr s
 	}
 
 	// Parse date
-	date, err := nowCfg.Parse(dateStr)
+	date, err := parseHalfDay(dateStr, nowCfg, time.Now())
+	if err != nil {
+		return false, 0, "", ErrDateParse
+	}
+
+	// Save price
+	return d.saveUserPrice(user, user, group, bells, date)
+}
+
+// SaveMemberPrice sets the sell price at Nook's Cranny of a group member at a given time on behalf of an author
+func (d *Database) SaveMemberPrice(a *tb.User, c *tb.Chat, member *User, bells uint32, dateStr string) (bool, uint32, string, error) {
+	// Get author and group
+	author, group, err := d.GetUserAndGroup(a, c)
+	if err != nil {
+		return false, 0, "", err
+	}
+
+	// Get now config with group timezone
+	nowCfg, err := group.NowConfig()
+	if err != nil {
+		return false, 0, "", err
+	}
+
+	// Parse date
+	date, err := parseHalfDay(dateStr, nowCfg, time.Now())
 	if err != nil {
 		return false, 0, "", ErrDateParse
 	}
 
 	// Save price
-	return d.saveUserPrice(user, group, bells, date)
+	return d.saveUserPrice(author, member, group, bells, date)
+}
+
+// SaveUserWeekPrices sets the sell prices at Nook's Cranny of several half-days of the current week at once, the half-days
+// are indexed from Monday AM
+func (d *Database) SaveUserWeekPrices(u *tb.User, c *tb.Chat, prices map[int]uint32) error {
+	// Get user and group
+	user, group, err := d.GetUserAndGroup(u, c)
+	if err != nil {
+		return err
+	}
+
+	// Get now config with group timezone
+	nowCfg, err := group.NowConfig()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	current := currentHalfDay(nowCfg, now)
+
+	// Prices of the half-days to come can't be known
+	future := -1
+	for halfDay := range prices {
+		if weekHalfDay(nowCfg, now, halfDay).After(current) && (future < 0 || halfDay < future) {
+			future = halfDay
+		}
+	}
+
+	if future >= 0 {
+		return &FutureHalfDayError{Date: weekHalfDay(nowCfg, now, future)}
+	}
+
+	// Save all the prices or none
+	return d.Transaction(func(tx *Database) error {
+		for halfDay, bells := range prices {
+			_, _, _, errs := tx.saveUserPrice(user, user, group, bells, weekHalfDay(nowCfg, now, halfDay))
+			if errs != nil {
+				return errs
+			}
+		}
+
+		return nil
+	})
 }
 
 // SaveUserCurrentPrice sets current sell price at Nook's Cranny
@@ -615,18 +1268,360 @@ func (d *Database) SaveUserCurrentPrice(u *tb.User, c *tb.Chat, bells uint32) (b
 		return false, 0, "", err
 	}
 
-	// Get current date and set it to 00:00:00 (AM) or 12:00:00 (PM)
-	currentDate := time.Now().In(nowCfg.TimeLocation)
+	// Save price
+	return d.saveUserPrice(user, user, group, bells, currentHalfDay(nowCfg, time.Now()))
+}
 
-	amDate := nowCfg.With(currentDate).BeginningOfDay()
-	pmDate := amDate.Add(time.Hour * 12)
+/*******************
+ Model: AuditEntry
+********************/
 
-	if currentDate.Before(pmDate) {
-		currentDate = amDate
-	} else {
-		currentDate = pmDate
+/* Private methods */
+
+// saveAuditEntry records a change made by an author to a record of an User in a Group
+func (d *Database) saveAuditEntry(author *User, u *User, g *Group, record AuditRecord, date time.Time, old AuditValues, new AuditValues) error {
+	entry := &AuditEntry{
+		GroupID:  g.ID,
+		UserID:   u.ID,
+		AuthorID: author.ID,
+		Record:   record,
+		Date:     date,
+		Old:      old,
+		New:      new,
 	}
 
-	// Save price
-	return d.saveUserPrice(user, group, bells, currentDate)
+	err := d.DB.Create(entry).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error saving audit entry")
+	}
+
+	return err
+}
+
+// getUserRecord returns a record of an User in a Group in a given date and its values, the record is a *Price, *Owned
+// or *IslandPrice that is new if it doesn't exist
+func (d *Database) getUserRecord(userID int64, groupID int64, record AuditRecord, date time.Time) (interface{}, AuditValues, error) {
+	var model interface{}
+
+	switch record {
+	case AuditPrice:
+		model = &Price{}
+	case AuditOwned:
+		model = &Owned{}
+	case AuditIslandPrice:
+		model = &IslandPrice{}
+	}
+
+	err := d.DB.Where("user_id = ? AND group_id = ? AND date = ?", userID, groupID, date).First(model).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return model, AuditValues{}, nil
+		}
+
+		log.Error().Str("module", "database").Err(err).Str("record", string(record)).Msg("error getting user record")
+		return nil, AuditValues{}, err
+	}
+
+	values := AuditValues{Exists: true}
+
+	switch r := model.(type) {
+	case *Price:
+		values.Bells = r.Bells
+	case *Owned:
+		values.Units = r.Units
+		values.Bells = r.Bells
+	case *IslandPrice:
+		values.Bells = r.Bells
+		values.FirstBuy = r.FirstBuy
+	}
+
+	return model, values, nil
+}
+
+// setUserRecord sets the values of a record of an User in a Group in a given date, creating or deleting it if needed
+func (d *Database) setUserRecord(userID int64, groupID int64, record AuditRecord, date time.Time, values AuditValues) error {
+	model, current, err := d.getUserRecord(userID, groupID, record, date)
+	if err != nil {
+		return err
+	}
+
+	if !values.Exists {
+		if !current.Exists {
+			return nil
+		}
+
+		// Removing turnips would remove their sales too
+		if owned, ok := model.(*Owned); ok {
+			sales := 0
+
+			err = d.DB.Model(&Sale{}).Where("owned_id = ?", owned.ID).Count(&sales).Error
+			if err != nil {
+				log.Error().Str("module", "database").Err(err).Msg("error counting owned sales")
+				return err
+			}
+
+			if sales > 0 {
+				return ErrHasSales
+			}
+		}
+
+		err = d.DB.Delete(model).Error
+		if err != nil {
+			log.Error().Str("module", "database").Err(err).Str("record", string(record)).Msg("error deleting user record")
+		}
+
+		return err
+	}
+
+	switch r := model.(type) {
+	case *Price:
+		r.UserID, r.GroupID, r.Date = userID, groupID, date
+		r.Bells = values.Bells
+	case *Owned:
+		r.UserID, r.GroupID, r.Date = userID, groupID, date
+		r.Units = values.Units
+		r.Bells = values.Bells
+	case *IslandPrice:
+		r.UserID, r.GroupID, r.Date = userID, groupID, date
+		r.Bells = values.Bells
+		r.FirstBuy = values.FirstBuy
+	}
+
+	err = d.DB.Save(model).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Str("record", string(record)).Msg("error saving user record")
+	}
+
+	return err
+}
+
+// saveUserRecord sets the values of a record of an User in a Group in a given date recording the change made by an
+// author, returns the previous values and if the record changed
+func (d *Database) saveUserRecord(author *User, u *User, g *Group, record AuditRecord, date time.Time, values AuditValues) (AuditValues, bool, error) {
+	_, current, err := d.getUserRecord(u.ID, g.ID, record, date)
+	if err != nil {
+		return current, false, err
+	}
+
+	if current == values {
+		return current, false, nil
+	}
+
+	err = d.Transaction(func(tx *Database) error {
+		errs := tx.setUserRecord(u.ID, g.ID, record, date, values)
+		if errs != nil {
+			return errs
+		}
+
+		return tx.saveAuditEntry(author, u, g, record, date, current, values)
+	})
+
+	return current, err == nil, err
+}
+
+// clearUserRecord removes a record of an User in a Group in a given date recording the change made by an author
+func (d *Database) clearUserRecord(author *User, u *User, g *Group, record AuditRecord, date time.Time) error {
+	_, current, err := d.getUserRecord(u.ID, g.ID, record, date)
+	if err != nil {
+		return err
+	}
+
+	if !current.Exists {
+		return ErrNoRecord
+	}
+
+	return d.Transaction(func(tx *Database) error {
+		errs := tx.setUserRecord(u.ID, g.ID, record, date, AuditValues{})
+		if errs != nil {
+			return errs
+		}
+
+		return tx.saveAuditEntry(author, u, g, record, date, current, AuditValues{})
+	})
+}
+
+/* Public methods */
+
+// ClearUserRecord removes a record of the user in the group: the price of a given date or this week owned or island price
+func (d *Database) ClearUserRecord(u *tb.User, c *tb.Chat, record AuditRecord, dateStr string) (string, error) {
+	// Get user
+	user, err := d.GetUser(u)
+	if err != nil {
+		return "", err
+	}
+
+	return d.ClearMemberRecord(u, c, user, record, dateStr)
+}
+
+// ClearMemberRecord removes a record of a group member on behalf of an author, see ClearUserRecord
+func (d *Database) ClearMemberRecord(a *tb.User, c *tb.Chat, member *User, record AuditRecord, dateStr string) (string, error) {
+	// Get author and group
+	author, group, err := d.GetUserAndGroup(a, c)
+	if err != nil {
+		return "", err
+	}
+
+	// Get now config with group timezone
+	nowCfg, err := group.NowConfig()
+	if err != nil {
+		return "", err
+	}
+
+	// Prices are per half-day, the rest per week
+	date := nowCfg.With(time.Now().In(nowCfg.TimeLocation)).BeginningOfWeek()
+
+	if record == AuditPrice {
+		date, err = parseHalfDay(dateStr, nowCfg, time.Now())
+		if err != nil {
+			return "", ErrDateParse
+		}
+	}
+
+	return date.Format(timeFormatAMPM), d.clearUserRecord(author, member, group, record, date)
+}
+
+// UndoUserLastChange reverts the last change the user made to their records in the group and returns its entries
+func (d *Database) UndoUserLastChange(u *tb.User, c *tb.Chat) ([]*AuditEntry, error) {
+	// Get user and group
+	user, group, err := d.GetUserAndGroup(u, c)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the last change entries
+	last := &AuditEntry{}
+
+	query := d.DB.Where("user_id = ? AND group_id = ? AND author_id = ? AND undone = ?", user.ID, group.ID, user.ID, false)
+
+	err = query.Order("changed_at DESC, id DESC").First(last).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrNoChanges
+		}
+
+		log.Error().Str("module", "database").Err(err).Msg("error getting last audit entry")
+		return nil, err
+	}
+
+	// Sales and known patterns can't be undone, neither the changes made before them
+	unaudited := 0
+
+	ownedIDs := d.DB.Model(&Owned{}).Select("id").Where("user_id = ? AND group_id = ?", user.ID, group.ID).SubQuery()
+
+	err = d.DB.Model(&Sale{}).Where("owned_id IN ? AND changed_at > ?", ownedIDs, last.ChangedAt).Count(&unaudited).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error counting sales since last change")
+		return nil, err
+	}
+
+	if unaudited > 0 {
+		return nil, ErrUnauditedSince
+	}
+
+	err = d.DB.Model(&KnownPattern{}).Where("user_id = ? AND group_id = ? AND changed_at > ?", user.ID, group.ID, last.ChangedAt).Count(&unaudited).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error counting known patterns since last change")
+		return nil, err
+	}
+
+	if unaudited > 0 {
+		return nil, ErrUnauditedSince
+	}
+
+	entries := []*AuditEntry{}
+
+	err = query.Where("changed_at = ?", last.ChangedAt).Order("id DESC").Find(&entries).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error getting last change audit entries")
+		return nil, err
+	}
+
+	// Restore the previous values, newest first, unless someone changed them afterwards
+	err = d.Transaction(func(tx *Database) error {
+		for _, entry := range entries {
+			_, current, errs := tx.getUserRecord(entry.UserID, entry.GroupID, entry.Record, entry.Date)
+			if errs != nil {
+				return errs
+			}
+
+			if current != entry.New {
+				return ErrChangedSince
+			}
+
+			errs = tx.setUserRecord(entry.UserID, entry.GroupID, entry.Record, entry.Date, entry.Old)
+			if errs != nil {
+				return errs
+			}
+
+			entry.Undone = true
+
+			errs = tx.DB.Model(entry).Update("undone", true).Error
+			if errs != nil {
+				log.Error().Str("module", "database").Err(errs).Msg("error saving audit entry")
+				return errs
+			}
+		}
+
+		return nil
+	})
+
+	return entries, err
+}
+
+// ImportUserData saves the records and known patterns of an import for the user in the group, all of them or none,
+// returns how many records changed
+func (d *Database) ImportUserData(u *tb.User, c *tb.Chat, data *UserImport) (int, error) {
+	// Get user and group
+	user, group, err := d.GetUserAndGroup(u, c)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+
+	err = d.Transaction(func(tx *Database) error {
+		for _, record := range data.Records {
+			_, saved, errs := tx.saveUserRecord(user, user, group, record.Record, record.Date, record.Values)
+			if errs != nil {
+				return errs
+			}
+
+			if saved {
+				changed++
+			}
+		}
+
+		for _, knownPattern := range data.KnownPatterns {
+			_, _, errs := tx.saveUserKnownPattern(user, group, knownPattern.Pattern, knownPattern.Date)
+			if errs != nil {
+				return errs
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return changed, nil
+}
+
+// GetMemberAuditEntries returns the last changes to the records of a group member, newest first
+func (d *Database) GetMemberAuditEntries(c *tb.Chat, member *User, limit int) ([]*AuditEntry, error) {
+	// Get group
+	group, err := d.GetGroup(c)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*AuditEntry{}
+
+	err = d.DB.Preload("Author").Where("user_id = ? AND group_id = ?", member.ID, group.ID).Order("changed_at DESC, id DESC").Limit(limit).Find(&entries).Error
+	if err != nil {
+		log.Error().Str("module", "database").Err(err).Msg("error getting member audit entries")
+	}
+
+	return entries, err
 }
@@ -0,0 +1,300 @@
+// Copyright (c) 2020 Sergio Conde skgsergio@gmail.com
+//
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU General Public License as published by the Free Software
+// Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY
+// WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+// PARTICULAR PURPOSE. See the GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along with
+// this program. If not, see <https://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tb "gopkg.in/tucnak/telebot.v3"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	schedulerInterval  = 30 * time.Second
+	weeklySummaryClock = "10:00"
+	rotWarningClock    = "18:00"
+)
+
+// scheduledJob is a task that runs for a group at some times of the day in the group time zone
+type scheduledJob struct {
+	name   string
+	days   []time.Weekday
+	clocks func(g *Group) []string
+	run    func(g *Group, at time.Time)
+}
+
+// Scheduler runs the scheduled jobs of every group
+type Scheduler struct {
+	telegram *Telegram
+	jobs     []scheduledJob
+	last     time.Time
+}
+
+// NewScheduler returns a Scheduler that sends its messages using the given Telegram bot
+func NewScheduler(t *Telegram) *Scheduler {
+	s := &Scheduler{
+		telegram: t,
+		last:     time.Now(),
+	}
+
+	s.jobs = []scheduledJob{
+		{
+			name: "sell_reminder",
+			days: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday},
+			clocks: func(g *Group) []string {
+				return g.SellReminderTimes()
+			},
+			run: s.sellReminder,
+		},
+		{
+			name: "buy_reminder",
+			days: []time.Weekday{turnipSellDay},
+			clocks: func(g *Group) []string {
+				if g.BuyReminder == "" {
+					return []string{}
+				}
+
+				return []string{g.BuyReminder}
+			},
+			run: s.buyReminder,
+		},
+		{
+			name: "weekly_summary",
+			days: []time.Weekday{turnipSellDay},
+			clocks: func(g *Group) []string {
+				return []string{weeklySummaryClock}
+			},
+			run: s.weeklySummary,
+		},
+		{
+			name: "rot_warning",
+			days: []time.Weekday{time.Saturday},
+			clocks: func(g *Group) []string {
+				return []string{rotWarningClock}
+			},
+			run: s.rotWarning,
+		},
+	}
+
+	return s
+}
+
+// Start runs the scheduler loop
+func (s *Scheduler) Start() {
+	log.Info().Str("module", "scheduler").Msg("start scheduler")
+
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.tick(now)
+	}
+}
+
+// tick runs the jobs scheduled since the last tick
+func (s *Scheduler) tick(now time.Time) {
+	groups, err := db.GetGroups()
+	if err != nil {
+		log.Error().Str("module", "scheduler").Err(err).Msg("error getting groups, retrying in next tick")
+		return
+	}
+
+	for _, group := range groups {
+		location, errl := time.LoadLocation(group.TZ)
+		if errl != nil {
+			log.Error().Str("module", "scheduler").Err(errl).Int64("group_id", group.ID).Msg("error loading timezone")
+			continue
+		}
+
+		for _, job := range s.jobs {
+			for _, clock := range job.clocks(group) {
+				at, ok := scheduledBetween(clock, location, s.last, now)
+				if !ok || !weekdayIn(at.Weekday(), job.days) {
+					continue
+				}
+
+				log.Info().Str("module", "scheduler").Str("job", job.name).Int64("group_id", group.ID).Time("at", at).Msg("running job")
+
+				go job.run(group, at)
+			}
+		}
+	}
+
+	s.last = now
+}
+
+// scheduledBetween returns the time a HH:MM clock in a location happened between two times, if it did
+func scheduledBetween(clock string, location *time.Location, from time.Time, to time.Time) (time.Time, bool) {
+	c, err := time.Parse("15:04", clock)
+	if err != nil {
+		log.Error().Str("module", "scheduler").Err(err).Str("clock", clock).Msg("invalid clock")
+		return time.Time{}, false
+	}
+
+	localTo := to.In(location)
+
+	// Check today and yesterday in case the interval crossed midnight
+	for _, day := range []time.Time{localTo, localTo.AddDate(0, 0, -1)} {
+		at := time.Date(day.Year(), day.Month(), day.Day(), c.Hour(), c.Minute(), 0, 0, location)
+
+		if at.After(from) && !at.After(to) {
+			return at, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// weekdayIn returns if a weekday is in a list of weekdays
+func weekdayIn(day time.Weekday, days []time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sellReminder reminds the group members that didn't save the price of the current half day
+func (s *Scheduler) sellReminder(g *Group, at time.Time) {
+	texts := langTexts(g.Lang)
+
+	users, err := db.GetGroupWeekUsers(g, at)
+	if err != nil {
+		return
+	}
+
+	prices, date, err := db.getGroupCurrentPrices(g)
+	if err != nil {
+		return
+	}
+
+	saved := map[int64]bool{}
+	for _, price := range prices {
+		saved[price.UserID] = true
+	}
+
+	missing := []string{}
+	for _, user := range users {
+		if !saved[user.ID] {
+			missing = append(missing, user.Mention())
+		}
+	}
+
+	if len(missing) == 0 {
+		return
+	}
+
+	s.telegram.send(&tb.Chat{ID: g.ID}, fmt.Sprintf(texts.Reminders.Sell, date, strings.Join(missing, ", "), texts.Sell.Cmd))
+}
+
+// buyReminder reminds the group to buy turnips and save them
+func (s *Scheduler) buyReminder(g *Group, _ time.Time) {
+	texts := langTexts(g.Lang)
+
+	s.telegram.send(&tb.Chat{ID: g.ID}, fmt.Sprintf(texts.BuyReminder.Reminder, texts.Buy.Cmd))
+}
+
+// weeklySummary posts the leaderboard of the week that just ended and the highest price ever seen
+func (s *Scheduler) weeklySummary(g *Group, at time.Time) {
+	texts := langTexts(g.Lang)
+
+	nowCfg, err := g.NowConfig()
+	if err != nil {
+		return
+	}
+
+	bowDate := nowCfg.With(at.In(nowCfg.TimeLocation)).BeginningOfWeek()
+
+	lastWeek, err := NewLeaderboard(g, bowDate.AddDate(0, 0, -7), bowDate)
+	if err != nil {
+		return
+	}
+
+	// Don't bother groups that didn't play last week
+	if len(lastWeek.Entries) == 0 && lastWeek.BestPrice == nil {
+		return
+	}
+
+	allTime, err := NewLeaderboard(g, time.Time{}, bowDate)
+	if err != nil {
+		return
+	}
+
+	lines := []string{texts.Leaderboard.Summary, lastWeek.Text(texts, texts.Leaderboard.LastWeek)}
+
+	if bestPrice := allTime.BestPriceText(texts, nowCfg.TimeLocation); bestPrice != "" {
+		lines = append(lines, bestPrice)
+	}
+
+	s.telegram.send(&tb.Chat{ID: g.ID}, strings.Join(lines, "\n\n"))
+}
+
+// rotWarning warns the group members with unsold turnips that they will rot tomorrow
+func (s *Scheduler) rotWarning(g *Group, at time.Time) {
+	texts := langTexts(g.Lang)
+
+	nowCfg, err := g.NowConfig()
+	if err != nil {
+		return
+	}
+
+	bowDate := nowCfg.With(at.In(nowCfg.TimeLocation)).BeginningOfWeek()
+
+	owneds, err := db.getGroupOwneds(g, bowDate, bowDate.AddDate(0, 0, 7))
+	if err != nil {
+		return
+	}
+
+	lines := []string{texts.RotWarning.Warning}
+
+	for _, owned := range owneds {
+		remaining := owned.RemainingUnits()
+		if remaining == 0 {
+			continue
+		}
+
+		prices, errp := db.getUserWeekPrices(&owned.User, g, at)
+		if errp != nil {
+			return
+		}
+
+		var bestPrice uint32 = 0
+		for _, price := range prices {
+			if price.Bells > bestPrice {
+				bestPrice = price.Bells
+			}
+		}
+
+		loss := remaining * owned.Bells
+
+		if bestPrice == 0 {
+			lines = append(lines, fmt.Sprintf(texts.RotWarning.MemberNoPrice, owned.User.Mention(), remaining, loss, texts.Bells))
+		} else {
+			lines = append(lines, fmt.Sprintf(texts.RotWarning.Member, owned.User.Mention(), remaining, bestPrice, texts.Bells, loss, texts.Bells))
+		}
+	}
+
+	if len(lines) == 1 {
+		return
+	}
+
+	s.telegram.send(&tb.Chat{ID: g.ID}, strings.Join(lines, "\n"))
+}
@@ -17,6 +17,7 @@ package main
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	tb "gopkg.in/tucnak/telebot.v3"
@@ -28,6 +29,61 @@ import (
 type Telegram struct {
 	bot                *tb.Bot
 	handlersRegistered bool
+
+	prompts     map[promptKey]*pricePrompt
+	promptsLock sync.Mutex
+
+	charts     map[chartKey]*cachedChart
+	chartsLock sync.Mutex
+}
+
+// promptKey identifies the user being asked something in a chat
+type promptKey struct {
+	chatID int64
+	userID int64
+}
+
+// pricePrompt is a question for the price of a half-day of a group waiting for an answer until the week is over
+type pricePrompt struct {
+	chat    *tb.Chat
+	date    string
+	expires time.Time
+	msg     *tb.Message
+}
+
+// botCommand is a command of the bot in a language
+type botCommand struct {
+	cmd     string
+	menu    string
+	admin   bool
+	handler tb.HandlerFunc
+}
+
+// commandScope is the scope of the users that see a list of commands in the Telegram menu, chat scopes need the chat
+type commandScope struct {
+	Type   string `json:"type"`
+	ChatID int64  `json:"chat_id,omitempty"`
+}
+
+// chartKey identifies the inline chart of an user in a group
+type chartKey struct {
+	userID  int64
+	groupID int64
+}
+
+// chartVersion is what an inline chart is drawn from, the chart is outdated when any of it changes
+type chartVersion struct {
+	texts  *Texts
+	title  string
+	start  int64
+	week   string
+	bought uint32
+}
+
+// cachedChart is the file id of an inline chart uploaded to the cache chat
+type cachedChart struct {
+	version chartVersion
+	fileID  string
 }
 
 // NewBot returns a Telegram bot
@@ -46,12 +102,13 @@ func NewBot(token string) (*Telegram, error) {
 
 	log.Info().Str("module", "telegram").Int64("id", bot.Me.ID).Str("name", bot.Me.FirstName).Str("username", bot.Me.Username).Msg("connected to telegram")
 
-	return &Telegram{bot: bot}, nil
+	return &Telegram{bot: bot, prompts: map[promptKey]*pricePrompt{}, charts: map[chartKey]*cachedChart{}}, nil
 }
 
 // Start starts polling for telegram updates
 func (t *Telegram) Start() {
 	t.registerHandlers()
+	t.setCommands()
 
 	log.Info().Str("module", "telegram").Msg("start polling")
 	t.bot.Start()
@@ -68,20 +125,135 @@ func (t *Telegram) registerHandlers() {
 	t.bot.Handle("/start", t.handleStart)
 	t.bot.Handle(tb.OnAddedToGroup, t.handleAddedToGroup)
 	t.bot.Handle(tb.OnMigration, t.handleGroupMigration)
-	t.bot.Handle(fmt.Sprintf("/%s", texts.Help.Cmd), t.handleHelpCmd)
-	t.bot.Handle(fmt.Sprintf("/%s", texts.Admin.Cmd), t.handleAdminCmd)
-	t.bot.Handle(fmt.Sprintf("/%s", texts.Buy.Cmd), t.handleBuyCmd)
-	t.bot.Handle(fmt.Sprintf("/%s", texts.IslandPrice.Cmd), t.handleIslandPriceCmd)
-	t.bot.Handle(fmt.Sprintf("/%s", texts.Sell.Cmd), t.handleSellCmd)
-	t.bot.Handle(fmt.Sprintf("/%s", texts.List.Cmd), t.handleListCmd)
-	t.bot.Handle(fmt.Sprintf("/%s", texts.Chart.Cmd), t.handleChartCmd)
-	t.bot.Handle(fmt.Sprintf("/%s", texts.Turnips.Cmd), t.handleTurnipsCmd)
-	t.bot.Handle(fmt.Sprintf("/%s", texts.Delete.Cmd), t.handleDeleteCmd)
-	t.bot.Handle(fmt.Sprintf("/%s", texts.ChangeTZ.Cmd), t.handleChangeTZCmd)
+	t.bot.Handle(&tb.Btn{Unique: sellHalfDayBtn}, t.handleSellHalfDayBtn)
+	t.bot.Handle(&tb.Btn{Unique: sellConfirmBtn}, t.handleSellConfirmBtn)
+	t.bot.Handle(&tb.Btn{Unique: sellCancelBtn}, t.handleSellCancelBtn)
+	t.bot.Handle(tb.OnText, t.handleText)
+	t.bot.Handle(tb.OnQuery, t.handleQuery)
+
+	// Commands are available with the names of every language
+	for _, texts := range langs {
+		for _, cmd := range t.commands(texts) {
+			t.bot.Handle(fmt.Sprintf("/%s", cmd.cmd), cmd.handler)
+		}
+	}
 
 	t.handlersRegistered = true
 }
 
+// commands returns the commands of the bot in a language, in the order they are shown in the Telegram menu
+func (t *Telegram) commands(texts *Texts) []botCommand {
+	return []botCommand{
+		{texts.Help.Cmd, texts.Help.Menu, false, t.handleHelpCmd},
+		{texts.Admin.Cmd, texts.Admin.Menu, false, t.handleAdminCmd},
+		{texts.Dashboard.Cmd, texts.Dashboard.Menu, false, t.handleDashboardCmd},
+		{texts.List.Cmd, texts.List.Menu, false, t.handleListCmd},
+		{texts.Chart.Cmd, texts.Chart.Menu, false, t.handleChartCmd},
+		{texts.Advice.Cmd, texts.Advice.Menu, false, t.handleAdviceCmd},
+		{texts.Islands.Cmd, texts.Islands.Menu, false, t.handleIslandsCmd},
+		{texts.Leaderboard.Cmd, texts.Leaderboard.Menu, false, t.handleLeaderboardCmd},
+		{texts.Turnips.Cmd, texts.Turnips.Menu, false, t.handleTurnipsCmd},
+		{texts.Buy.Cmd, texts.Buy.Menu, false, t.handleBuyCmd},
+		{texts.IslandPrice.Cmd, texts.IslandPrice.Menu, false, t.handleIslandPriceCmd},
+		{texts.Sell.Cmd, texts.Sell.Menu, false, t.handleSellCmd},
+		{texts.Week.Cmd, texts.Week.Menu, false, t.handleWeekCmd},
+		{texts.Sold.Cmd, texts.Sold.Menu, false, t.handleSoldCmd},
+		{texts.Undo.Cmd, texts.Undo.Menu, false, t.handleUndoCmd},
+		{texts.Clear.Cmd, texts.Clear.Menu, false, t.handleClearCmd},
+		{texts.Import.Cmd, texts.Import.Menu, false, t.handleImportCmd},
+		{texts.SpikeAlerts.Cmd, texts.SpikeAlerts.Menu, false, t.handleSpikeAlertsCmd},
+		{texts.LastPattern.Cmd, texts.LastPattern.Menu, false, t.handleLastPatternCmd},
+		{texts.Lang.Cmd, texts.Lang.Menu, false, t.handleLangCmd},
+		{texts.Delete.Cmd, texts.Delete.Menu, true, t.handleDeleteCmd},
+		{texts.ChangeTZ.Cmd, texts.ChangeTZ.Menu, true, t.handleChangeTZCmd},
+		{texts.Alerts.Cmd, texts.Alerts.Menu, true, t.handleAlertsCmd},
+		{texts.Reminders.Cmd, texts.Reminders.Menu, true, t.handleRemindersCmd},
+		{texts.BuyReminder.Cmd, texts.BuyReminder.Menu, true, t.handleBuyReminderCmd},
+		{texts.Fix.Cmd, texts.Fix.Menu, true, t.handleFixCmd},
+		{texts.Remove.Cmd, texts.Remove.Menu, true, t.handleRemoveCmd},
+		{texts.History.Cmd, texts.History.Menu, true, t.handleHistoryCmd},
+		{texts.Export.Cmd, texts.Export.Menu, true, t.handleExportCmd},
+	}
+}
+
+// setCommands sets the commands shown in the Telegram menu for every language, the default language ones are shown
+// to users of other languages and group admins see the admin commands too
+func (t *Telegram) setCommands() {
+	log.Info().Str("module", "telegram").Msg("setting commands")
+
+	for lang, texts := range langs {
+		// Telegram uses the list without language code for languages without their own list
+		langCode := lang
+		if texts == defaultTexts {
+			langCode = ""
+		} else if lang == defaultLangFile {
+			continue
+		}
+
+		userCmds, adminCmds := t.menuCommands(texts)
+
+		t.setLangCommands(userCmds, commandScope{Type: "default"}, langCode)
+		t.setLangCommands(adminCmds, commandScope{Type: "all_chat_administrators"}, langCode)
+	}
+}
+
+// setChatCommands sets the commands shown in the Telegram menu of a group in its language, empty to go back to the
+// ones of each user language
+func (t *Telegram) setChatCommands(chat *tb.Chat, lang string) {
+	userScope := commandScope{Type: "chat", ChatID: chat.ID}
+	adminScope := commandScope{Type: "chat_administrators", ChatID: chat.ID}
+
+	if lang == "" {
+		t.deleteCommands(userScope)
+		t.deleteCommands(adminScope)
+		return
+	}
+
+	userCmds, adminCmds := t.menuCommands(langTexts(lang))
+
+	t.setLangCommands(userCmds, userScope, "")
+	t.setLangCommands(adminCmds, adminScope, "")
+}
+
+// menuCommands returns the commands of a language shown to users and to group admins in the Telegram menu
+func (t *Telegram) menuCommands(texts *Texts) ([]tb.Command, []tb.Command) {
+	userCmds := []tb.Command{}
+	adminCmds := []tb.Command{}
+
+	for _, cmd := range t.commands(texts) {
+		if !cmd.admin {
+			userCmds = append(userCmds, tb.Command{Text: cmd.cmd, Description: cmd.menu})
+		}
+
+		adminCmds = append(adminCmds, tb.Command{Text: cmd.cmd, Description: cmd.menu})
+	}
+
+	return userCmds, adminCmds
+}
+
+// setLangCommands sets the commands of a language code for a scope, empty language code for every language
+func (t *Telegram) setLangCommands(cmds []tb.Command, scope commandScope, langCode string) {
+	params := map[string]interface{}{
+		"commands": cmds,
+		"scope":    scope,
+	}
+
+	if langCode != "" {
+		params["language_code"] = langCode
+	}
+
+	if _, err := t.bot.Raw("setMyCommands", params); err != nil {
+		log.Error().Str("module", "telegram").Err(err).Str("scope", scope.Type).Int64("chat_id", scope.ChatID).Str("lang", langCode).Msg("error setting commands")
+	}
+}
+
+// deleteCommands deletes the commands of a scope for every language, so the ones of a broader scope are shown
+func (t *Telegram) deleteCommands(scope commandScope) {
+	if _, err := t.bot.Raw("deleteMyCommands", map[string]interface{}{"scope": scope}); err != nil {
+		log.Error().Str("module", "telegram").Err(err).Str("scope", scope.Type).Int64("chat_id", scope.ChatID).Msg("error deleting commands")
+	}
+}
+
 func (t *Telegram) isSuperAdmin(user *tb.User) bool {
 	for _, uid := range superAdmins {
 		if user.ID == uid {
@@ -102,6 +274,138 @@ func (t *Telegram) isGroupAdmin(chat *tb.Chat, user *tb.User) (bool, error) {
 	return (cm.Role == tb.Creator || cm.Role == tb.Administrator), nil
 }
 
+// groupMemberOf returns the current data of an user in a group, nil if they are not in the group
+func (t *Telegram) groupMemberOf(chat *tb.Chat, userID int64) (*tb.User, error) {
+	cm, err := t.bot.ChatMemberOf(chat, &tb.User{ID: userID})
+	if err != nil {
+		log.Error().Str("module", "telegram").Err(err).Msg("error checking group member")
+		return nil, err
+	}
+
+	if cm.Role == tb.Left || cm.Role == tb.Kicked {
+		return nil, nil
+	}
+
+	return cm.User, nil
+}
+
+// texts returns the texts for an user in a chat: the user language if set, otherwise the group one, private chats and
+// chatless updates use the default language. Unknown users and groups are not created.
+func (t *Telegram) texts(c *tb.Chat, u *tb.User) *Texts {
+	if u != nil {
+		user, err := db.GetUserByID(u.ID)
+		if err == nil && user != nil && user.Lang != "" {
+			return langTexts(user.Lang)
+		}
+	}
+
+	if c != nil && c.Type != tb.ChatPrivate {
+		group, err := db.GetGroupByID(c.ID)
+		if err == nil && group != nil {
+			return langTexts(group.Lang)
+		}
+	}
+
+	return defaultTexts
+}
+
+// groupChat returns the group chat a message acts on: the chat itself in groups and the dashboard selected group in
+// private chats, nil if no group has been selected
+func (t *Telegram) groupChat(m *tb.Message) (*tb.Chat, error) {
+	if !m.Private() {
+		return m.Chat, nil
+	}
+
+	group, err := db.GetUserDashboardGroup(m.Sender)
+	if err != nil || group == nil {
+		return nil, err
+	}
+
+	return &tb.Chat{ID: group.ID, Title: group.Title, Type: tb.ChatGroup}, nil
+}
+
+// setPricePrompt stores the price question asked to an user in a chat, replacing any previous one
+func (t *Telegram) setPricePrompt(chat *tb.Chat, user *tb.User, prompt *pricePrompt) {
+	t.promptsLock.Lock()
+	defer t.promptsLock.Unlock()
+
+	t.prompts[promptKey{chatID: chat.ID, userID: user.ID}] = prompt
+}
+
+// pricePrompt returns the price question a message answers, in private chats any message answers it. Questions of
+// past weeks are dropped.
+func (t *Telegram) pricePrompt(m *tb.Message) (*pricePrompt, bool) {
+	t.promptsLock.Lock()
+	defer t.promptsLock.Unlock()
+
+	key := promptKey{chatID: m.Chat.ID, userID: m.Sender.ID}
+
+	prompt, ok := t.prompts[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !time.Now().Before(prompt.expires) {
+		delete(t.prompts, key)
+		return nil, false
+	}
+
+	if !m.Private() && (m.ReplyTo == nil || m.ReplyTo.ID != prompt.msg.ID) {
+		return nil, false
+	}
+
+	return prompt, true
+}
+
+// clearPricePrompt removes the price question asked to the sender of a message
+func (t *Telegram) clearPricePrompt(m *tb.Message) {
+	t.promptsLock.Lock()
+	defer t.promptsLock.Unlock()
+
+	delete(t.prompts, promptKey{chatID: m.Chat.ID, userID: m.Sender.ID})
+}
+
+// uploadPhoto uploads a photo to the cache chat and returns its file id, empty if there is no cache chat or it failed.
+// It doesn't retry as it is used while answering inline queries, which have a short deadline.
+func (t *Telegram) uploadPhoto(photo *tb.Photo) string {
+	if cacheChatID == 0 {
+		return ""
+	}
+
+	msg, err := t.bot.Send(&tb.Chat{ID: cacheChatID}, photo, tb.Silent)
+	if err != nil {
+		log.Error().Str("module", "telegram").Err(err).Msg("error uploading photo to the cache chat")
+		return ""
+	}
+
+	if msg.Photo == nil {
+		return ""
+	}
+
+	return msg.Photo.FileID
+}
+
+// cachedChart returns the file id of the uploaded chart of an user in a group, empty if it is missing or outdated
+func (t *Telegram) cachedChart(key chartKey, version chartVersion) string {
+	t.chartsLock.Lock()
+	defer t.chartsLock.Unlock()
+
+	chart, ok := t.charts[key]
+	if !ok || chart.version != version {
+		return ""
+	}
+
+	return chart.fileID
+}
+
+// setCachedChart stores the file id of the uploaded chart of an user in a group, replacing any previous one
+func (t *Telegram) setCachedChart(key chartKey, version chartVersion, fileID string) {
+	t.chartsLock.Lock()
+	defer t.chartsLock.Unlock()
+
+	t.charts[key] = &cachedChart{version: version, fileID: fileID}
+}
+
 // send sends a message with error logging and retries
 func (t *Telegram) send(to tb.Recipient, what interface{}, options ...interface{}) *tb.Message {
 	hasParseMode := false
@@ -171,6 +475,11 @@ func (t *Telegram) reply(to *tb.Message, what interface{}, options ...interface{
 func (t *Telegram) cleanupChatMsgs(chat *tb.Chat, msgs []*tb.Message) {
 	var err error = nil
 
+	// Private chats are personal dashboards, there is nothing to clean up
+	if chat.Type == tb.ChatPrivate {
+		return
+	}
+
 	// Check if the group requires message deletion
 	group, err := db.GetGroup(chat)
 	if err != nil {
@@ -19,38 +19,60 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	// defaultLangFile is the texts file of the default language, it is not offered as a language to choose
+	defaultLangFile = "default"
+)
+
 // Texts represent the texts used in user parts of the bot
 type Texts struct {
+	LangName      string   `json:"lang_name"`
 	GroupOnly     string   `json:"group_only"`
 	JoinText      string   `json:"join_text"`
 	InternalError string   `json:"internal_error"`
 	InvalidParams string   `json:"invalid_parameters"`
 	Unprivileged  string   `json:"unprivileged"`
 	Bells         string   `json:"bells"`
+	On            string   `json:"on"`
+	Off           string   `json:"off"`
 	Days          []string `json:"days"`
 	DaysShort     []string `json:"days_short"`
 
+	Dates struct {
+		Today     []string `json:"today"`
+		Yesterday []string `json:"yesterday"`
+		AM        []string `json:"am"`
+		PM        []string `json:"pm"`
+	} `json:"dates"`
+
 	Patterns struct {
 		Random struct {
+			Key  string `json:"key"`
 			Name string `json:"name"`
 			Desc string `json:"desc"`
 		} `json:"random"`
 
 		BigSpike struct {
+			Key  string `json:"key"`
 			Name string `json:"name"`
 			Desc string `json:"desc"`
 		} `json:"big_spike"`
 
 		Falling struct {
+			Key  string `json:"key"`
 			Name string `json:"name"`
 			Desc string `json:"desc"`
 		} `json:"falling"`
 
 		SmallSpike struct {
+			Key  string `json:"key"`
 			Name string `json:"name"`
 			Desc string `json:"desc"`
 		} `json:"small_spike"`
@@ -62,47 +84,110 @@ type Texts struct {
 
 	Help struct {
 		Cmd           string `json:"cmd"`
+		Menu          string `json:"menu"`
 		Desc          string `json:"desc"`
 		AvailableCmds string `json:"available_cmds"`
 		CmdAdmin      string `json:"cmd_admin"`
 		AdminCmds     string `json:"admin_cmds"`
+		Reminders     string `json:"reminders"`
 	} `json:"help"`
 
+	Dashboard struct {
+		Cmd         string `json:"cmd"`
+		Menu        string `json:"menu"`
+		Params      string `json:"params"`
+		Desc        string `json:"desc"`
+		Start       string `json:"start"`
+		Groups      string `json:"groups"`
+		Group       string `json:"group"`
+		Selected    string `json:"selected"`
+		NoGroups    string `json:"no_groups"`
+		NoGroup     string `json:"no_group"`
+		Changed     string `json:"changed"`
+		PrivateOnly string `json:"private_only"`
+	} `json:"dashboard"`
+
+	Inline struct {
+		Title    string `json:"title"`
+		Prices   string `json:"prices"`
+		Chart    string `json:"chart"`
+		Summary  string `json:"summary"`
+		NoPrices string `json:"no_prices"`
+	} `json:"inline"`
+
 	Admin struct {
 		Cmd           string `json:"cmd"`
+		Menu          string `json:"menu"`
 		Desc          string `json:"desc"`
 		AvailableCmds string `json:"available_cmds"`
 	} `json:"admin"`
 
 	Buy struct {
-		Cmd         string `json:"cmd"`
-		Params      string `json:"params"`
-		Desc        string `json:"desc"`
-		Saved       string `json:"saved"`
-		Changed     string `json:"changed"`
-		UnitsModTen string `json:"units_mod_ten"`
+		Cmd          string `json:"cmd"`
+		Menu         string `json:"menu"`
+		Params       string `json:"params"`
+		Desc         string `json:"desc"`
+		Saved        string `json:"saved"`
+		Changed      string `json:"changed"`
+		UnitsModTen  string `json:"units_mod_ten"`
+		FirstBuyFlag string `json:"first_buy_flag"`
 	} `json:"buy"`
 
 	IslandPrice struct {
-		Cmd     string `json:"cmd"`
-		Params  string `json:"params"`
-		Desc    string `json:"desc"`
-		Saved   string `json:"saved"`
-		Changed string `json:"changed"`
+		Cmd      string `json:"cmd"`
+		Menu     string `json:"menu"`
+		Params   string `json:"params"`
+		Desc     string `json:"desc"`
+		Saved    string `json:"saved"`
+		Changed  string `json:"changed"`
+		FirstBuy string `json:"first_buy"`
 	} `json:"island_price"`
 
 	Sell struct {
 		Cmd           string `json:"cmd"`
+		Menu          string `json:"menu"`
 		Params        string `json:"params"`
 		Desc          string `json:"desc"`
 		Saved         string `json:"saved"`
 		Changed       string `json:"changed"`
 		InvalidDate   string `json:"invalid_date"`
 		NoMarketToday string `json:"no_market_today"`
+		Keyboard      string `json:"keyboard"`
+		Filled        string `json:"filled"`
+		NoHalfDays    string `json:"no_half_days"`
+		NotYours      string `json:"not_yours"`
+		AskBells      string `json:"ask_bells"`
+		InvalidBells  string `json:"invalid_bells"`
+		Unanswered    string `json:"unanswered"`
+		Outlier       string `json:"outlier"`
+		Confirm       string `json:"confirm"`
+		Cancel        string `json:"cancel"`
+		Cancelled     string `json:"cancelled"`
 	} `json:"sell"`
 
+	Week struct {
+		Cmd    string `json:"cmd"`
+		Menu   string `json:"menu"`
+		Params string `json:"params"`
+		Desc   string `json:"desc"`
+		Saved  string `json:"saved"`
+		Future string `json:"future"`
+	} `json:"week"`
+
+	Sold struct {
+		Cmd            string `json:"cmd"`
+		Menu           string `json:"menu"`
+		Params         string `json:"params"`
+		Desc           string `json:"desc"`
+		Saved          string `json:"saved"`
+		NoOwned        string `json:"no_owned"`
+		NotEnoughUnits string `json:"not_enough_units"`
+		NotThisWeek    string `json:"not_this_week"`
+	} `json:"sold"`
+
 	List struct {
 		Cmd      string `json:"cmd"`
+		Menu     string `json:"menu"`
 		Desc     string `json:"desc"`
 		Owned    string `json:"owned"`
 		Prices   string `json:"prices"`
@@ -110,28 +195,229 @@ type Texts struct {
 	} `json:"list"`
 
 	Chart struct {
+		Cmd           string `json:"cmd"`
+		Menu          string `json:"menu"`
+		Desc          string `json:"desc"`
+		NoPrices      string `json:"no_prices"`
+		TurnipProphet string `json:"turnip_prophet"`
+	} `json:"chart"`
+
+	Advice struct {
+		Cmd            string `json:"cmd"`
+		Menu           string `json:"menu"`
+		Desc           string `json:"desc"`
+		NoOwned        string `json:"no_owned"`
+		NoCurrentPrice string `json:"no_current_price"`
+		Now            string `json:"now"`
+		Later          string `json:"later"`
+		Sell           string `json:"sell"`
+		Hold           string `json:"hold"`
+		LastChance     string `json:"last_chance"`
+	} `json:"advice"`
+
+	Islands struct {
+		Cmd       string `json:"cmd"`
+		Menu      string `json:"menu"`
+		Desc      string `json:"desc"`
+		Ranking   string `json:"ranking"`
+		Island    string `json:"island"`
+		Unknown   string `json:"unknown"`
+		NoIslands string `json:"no_islands"`
+		Closed    string `json:"closed"`
+	} `json:"islands"`
+
+	Leaderboard struct {
+		Cmd       string `json:"cmd"`
+		Menu      string `json:"menu"`
+		Desc      string `json:"desc"`
+		ThisWeek  string `json:"this_week"`
+		LastWeek  string `json:"last_week"`
+		AllTime   string `json:"all_time"`
+		Entry     string `json:"entry"`
+		NoEntries string `json:"no_entries"`
+		BestPrice string `json:"best_price"`
+		Summary   string `json:"summary"`
+	} `json:"leaderboard"`
+
+	SpikeAlerts struct {
 		Cmd      string `json:"cmd"`
+		Menu     string `json:"menu"`
+		Params   string `json:"params"`
 		Desc     string `json:"desc"`
-		NoPrices string `json:"no_prices"`
-	} `json:"chart"`
+		Enabled  string `json:"enabled"`
+		Disabled string `json:"disabled"`
+		Alert    string `json:"alert"`
+		Owner    string `json:"owner"`
+		OptOut   string `json:"opt_out"`
+	} `json:"spike_alerts"`
 
 	Turnips struct {
 		Cmd      string `json:"cmd"`
+		Menu     string `json:"menu"`
 		Desc     string `json:"desc"`
 		Owneds   string `json:"owneds"`
+		Sold     string `json:"sold"`
 		NoOwneds string `json:"no_owneds"`
 	} `json:"turnips"`
 
+	Undo struct {
+		Cmd            string `json:"cmd"`
+		Menu           string `json:"menu"`
+		Desc           string `json:"desc"`
+		Done           string `json:"done"`
+		NoChanges      string `json:"no_changes"`
+		ChangedSince   string `json:"changed_since"`
+		UnauditedSince string `json:"unaudited_since"`
+	} `json:"undo"`
+
+	Clear struct {
+		Cmd                string `json:"cmd"`
+		Menu               string `json:"menu"`
+		Params             string `json:"params"`
+		Desc               string `json:"desc"`
+		Buy                string `json:"buy"`
+		Island             string `json:"island"`
+		PriceCleared       string `json:"price_cleared"`
+		OwnedCleared       string `json:"owned_cleared"`
+		IslandPriceCleared string `json:"island_price_cleared"`
+		NoRecord           string `json:"no_record"`
+		HasSales           string `json:"has_sales"`
+	} `json:"clear"`
+
+	Import struct {
+		Cmd          string `json:"cmd"`
+		Menu         string `json:"menu"`
+		Params       string `json:"params"`
+		Desc         string `json:"desc"`
+		Done         string `json:"done"`
+		Revert       string `json:"revert"`
+		PatternsKept string `json:"patterns_kept"`
+		InvalidQuery string `json:"invalid_query"`
+		InvalidCSV   string `json:"invalid_csv"`
+		InvalidRow   string `json:"invalid_row"`
+		NotYours     string `json:"not_yours"`
+		TooBig       string `json:"too_big"`
+	} `json:"import"`
+
+	Audit struct {
+		Price       string `json:"price"`
+		Owned       string `json:"owned"`
+		IslandPrice string `json:"island_price"`
+		None        string `json:"none"`
+		Units       string `json:"units"`
+		Change      string `json:"change"`
+	} `json:"audit"`
+
+	LastPattern struct {
+		Cmd     string `json:"cmd"`
+		Menu    string `json:"menu"`
+		Params  string `json:"params"`
+		Desc    string `json:"desc"`
+		Saved   string `json:"saved"`
+		Changed string `json:"changed"`
+	} `json:"last_pattern"`
+
 	Delete struct {
 		Cmd      string `json:"cmd"`
+		Menu     string `json:"menu"`
 		Params   string `json:"params"`
 		Desc     string `json:"desc"`
 		Done     string `json:"done"`
 		Disabled string `json:"disabled"`
 	} `json:"delete"`
 
+	Reminders struct {
+		Cmd      string `json:"cmd"`
+		Menu     string `json:"menu"`
+		Params   string `json:"params"`
+		Desc     string `json:"desc"`
+		Changed  string `json:"changed"`
+		Disabled string `json:"disabled"`
+		Sell     string `json:"sell"`
+	} `json:"reminders"`
+
+	BuyReminder struct {
+		Cmd      string `json:"cmd"`
+		Menu     string `json:"menu"`
+		Params   string `json:"params"`
+		Desc     string `json:"desc"`
+		Changed  string `json:"changed"`
+		Disabled string `json:"disabled"`
+		Reminder string `json:"reminder"`
+	} `json:"buy_reminder"`
+
+	RotWarning struct {
+		Warning       string `json:"warning"`
+		Member        string `json:"member"`
+		MemberNoPrice string `json:"member_no_price"`
+	} `json:"rot_warning"`
+
+	Alerts struct {
+		Cmd      string `json:"cmd"`
+		Menu     string `json:"menu"`
+		Params   string `json:"params"`
+		Desc     string `json:"desc"`
+		Changed  string `json:"changed"`
+		Disabled string `json:"disabled"`
+	} `json:"alerts"`
+
+	Fix struct {
+		Cmd     string `json:"cmd"`
+		Menu    string `json:"menu"`
+		Params  string `json:"params"`
+		Desc    string `json:"desc"`
+		Changed string `json:"changed"`
+	} `json:"fix"`
+
+	Remove struct {
+		Cmd     string `json:"cmd"`
+		Menu    string `json:"menu"`
+		Params  string `json:"params"`
+		Desc    string `json:"desc"`
+		Removed string `json:"removed"`
+	} `json:"remove"`
+
+	History struct {
+		Cmd       string `json:"cmd"`
+		Menu      string `json:"menu"`
+		Params    string `json:"params"`
+		Desc      string `json:"desc"`
+		Title     string `json:"title"`
+		Entry     string `json:"entry"`
+		Self      string `json:"self"`
+		Undone    string `json:"undone"`
+		NoEntries string `json:"no_entries"`
+	} `json:"history"`
+
+	Export struct {
+		Cmd     string `json:"cmd"`
+		Menu    string `json:"menu"`
+		Params  string `json:"params"`
+		Desc    string `json:"desc"`
+		Caption string `json:"caption"`
+	} `json:"export"`
+
+	Member struct {
+		Unknown string `json:"unknown"`
+		Missing string `json:"missing"`
+	} `json:"member"`
+
+	Lang struct {
+		Cmd       string `json:"cmd"`
+		Menu      string `json:"menu"`
+		Params    string `json:"params"`
+		Desc      string `json:"desc"`
+		AdminDesc string `json:"admin_desc"`
+		Available string `json:"available"`
+		Lang      string `json:"lang"`
+		Changed   string `json:"changed"`
+		Reset     string `json:"reset"`
+		Invalid   string `json:"invalid"`
+	} `json:"lang"`
+
 	ChangeTZ struct {
 		Cmd     string `json:"cmd"`
+		Menu    string `json:"menu"`
 		Params  string `json:"params"`
 		Desc    string `json:"desc"`
 		Changed string `json:"changed"`
@@ -139,6 +425,38 @@ type Texts struct {
 	} `json:"changetz"`
 }
 
+// PatternText returns the name and description of a pattern type
+func (t *Texts) PatternText(pat PatternType) (string, string) {
+	switch pat {
+	case Random:
+		return t.Patterns.Random.Name, t.Patterns.Random.Desc
+	case BigSpike:
+		return t.Patterns.BigSpike.Name, t.Patterns.BigSpike.Desc
+	case Falling:
+		return t.Patterns.Falling.Name, t.Patterns.Falling.Desc
+	case SmallSpike:
+		return t.Patterns.SmallSpike.Name, t.Patterns.SmallSpike.Desc
+	}
+
+	return "", ""
+}
+
+// PatternByKey returns the pattern type given its key
+func (t *Texts) PatternByKey(key string) (PatternType, bool) {
+	switch strings.ToLower(key) {
+	case t.Patterns.Random.Key:
+		return Random, true
+	case t.Patterns.BigSpike.Key:
+		return BigSpike, true
+	case t.Patterns.Falling.Key:
+		return Falling, true
+	case t.Patterns.SmallSpike.Key:
+		return SmallSpike, true
+	}
+
+	return 0, false
+}
+
 // LoadTexts load a language texts json file and returns it as Texts
 func LoadTexts(lang string) (*Texts, error) {
 	txtFile, err := os.Open(fmt.Sprintf("texts/%s.json", lang))
@@ -154,9 +472,54 @@ func LoadTexts(lang string) (*Texts, error) {
 
 	var txt = Texts{}
 	decoder := json.NewDecoder(txtFile)
-	if decoder.Decode(&txt) != nil {
+	if err = decoder.Decode(&txt); err != nil {
 		return nil, err
 	}
 
 	return &txt, nil
 }
+
+// LoadAllTexts loads all the language texts json files and returns them indexed by language
+func LoadAllTexts() (map[string]*Texts, error) {
+	files, err := filepath.Glob("texts/*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	all := map[string]*Texts{}
+
+	for _, file := range files {
+		lang := strings.TrimSuffix(filepath.Base(file), ".json")
+
+		all[lang], err = LoadTexts(lang)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	return all, nil
+}
+
+// langTexts returns the texts of a language, the default ones if the language is empty or unknown
+func langTexts(lang string) *Texts {
+	if txt, ok := langs[lang]; ok && lang != "" {
+		return txt
+	}
+
+	return defaultTexts
+}
+
+// availableLangs returns the sorted languages that can be chosen
+func availableLangs() []string {
+	available := []string{}
+
+	for lang := range langs {
+		if lang != defaultLangFile {
+			available = append(available, lang)
+		}
+	}
+
+	sort.Strings(available)
+
+	return available
+}
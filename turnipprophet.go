@@ -0,0 +1,55 @@
+// Copyright (c) 2020 Sergio Conde skgsergio@gmail.com
+//
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU General Public License as published by the Free Software
+// Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY
+// WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+// PARTICULAR PURPOSE. See the GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along with
+// this program. If not, see <https://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	turnipProphetBaseURL = "https://turnipprophet.io/"
+)
+
+// turnipProphetURL returns a link to Turnip Prophet pre-filled with a week, prices are the island price followed by the
+// sell prices from Monday AM separated by dots, empty if unknown, and the previous pattern is omitted if negative
+func turnipProphetURL(islandPrice uint32, prices [12]uint32, firstBuy bool, previous int) string {
+	fields := make([]string, 0, len(prices)+1)
+
+	for _, price := range append([]uint32{islandPrice}, prices[:]...) {
+		if price == 0 {
+			fields = append(fields, "")
+		} else {
+			fields = append(fields, strconv.FormatUint(uint64(price), 10))
+		}
+	}
+
+	// Trailing unknown prices are not needed
+	for len(fields) > 0 && fields[len(fields)-1] == "" {
+		fields = fields[:len(fields)-1]
+	}
+
+	query := url.Values{}
+	query.Set("prices", strings.Join(fields, "."))
+	query.Set("first", strconv.FormatBool(firstBuy))
+
+	if previous >= 0 {
+		query.Set("pattern", strconv.Itoa(previous))
+	}
+
+	return turnipProphetBaseURL + "?" + query.Encode()
+}
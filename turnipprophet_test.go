@@ -0,0 +1,114 @@
+// Copyright (c) 2020 Sergio Conde skgsergio@gmail.com
+//
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU General Public License as published by the Free Software
+// Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY
+// WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+// PARTICULAR PURPOSE. See the GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along with
+// this program. If not, see <https://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/now"
+)
+
+func TestTurnipProphetURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		islandPrice uint32
+		prices      [12]uint32
+		firstBuy    bool
+		previous    int
+		want        string
+	}{
+		{
+			name:     "empty week",
+			previous: -1,
+			want:     "https://turnipprophet.io/?first=false&prices=",
+		},
+		{
+			name:        "island price only",
+			islandPrice: 97,
+			previous:    -1,
+			want:        "https://turnipprophet.io/?first=false&prices=97",
+		},
+		{
+			name:        "full week with previous pattern",
+			islandPrice: 100,
+			prices:      [12]uint32{90, 86, 82, 78, 120, 160, 450, 200, 110, 80, 70, 60},
+			previous:    int(BigSpike),
+			want:        "https://turnipprophet.io/?first=false&pattern=1&prices=100.90.86.82.78.120.160.450.200.110.80.70.60",
+		},
+		{
+			name:        "missing prices in the middle and at the end",
+			islandPrice: 104,
+			prices:      [12]uint32{95, 0, 0, 88},
+			firstBuy:    true,
+			previous:    -1,
+			want:        "https://turnipprophet.io/?first=true&prices=104.95...88",
+		},
+		{
+			name:     "unknown island price",
+			prices:   [12]uint32{0, 110},
+			previous: int(SmallSpike),
+			want:     "https://turnipprophet.io/?first=false&pattern=3&prices=..110",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := turnipProphetURL(tt.islandPrice, tt.prices, tt.firstBuy, tt.previous)
+			if got != tt.want {
+				t.Errorf("turnipProphetURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTurnipProphetURLRoundTrip(t *testing.T) {
+	nowCfg := &now.Config{WeekStartDay: turnipSellDay, TimeLocation: time.UTC, TimeFormats: []string{timeFormatAMPM}}
+	week := time.Date(2020, 4, 22, 10, 0, 0, 0, time.UTC)
+	prices := [12]uint32{90, 0, 82, 78, 120, 160, 450, 200, 0, 0, 70}
+
+	data, err := parseTurnipProphetQuery(turnipProphetURL(100, prices, true, int(Falling)), nowCfg, week)
+	if err != nil {
+		t.Fatalf("parseTurnipProphetQuery() error = %v", err)
+	}
+
+	islandPrice := uint32(0)
+	firstBuy := false
+	got := [12]uint32{}
+	monday := time.Date(2020, 4, 20, 0, 0, 0, 0, time.UTC)
+
+	for _, record := range data.Records {
+		switch record.Record {
+		case AuditIslandPrice:
+			islandPrice = record.Values.Bells
+			firstBuy = record.Values.FirstBuy
+		case AuditPrice:
+			got[int(record.Date.Sub(monday)/(time.Hour*12))] = record.Values.Bells
+		}
+	}
+
+	if islandPrice != 100 || !firstBuy {
+		t.Errorf("island price = %d (first buy %v), want 100 (first buy true)", islandPrice, firstBuy)
+	}
+
+	if got != prices {
+		t.Errorf("prices = %v, want %v", got, prices)
+	}
+
+	if len(data.KnownPatterns) != 1 || data.KnownPatterns[0].Pattern != Falling || !data.KnownPatterns[0].Date.Equal(monday.AddDate(0, 0, -8)) {
+		t.Errorf("known patterns = %+v, want falling the previous week", data.KnownPatterns)
+	}
+}
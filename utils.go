@@ -17,6 +17,7 @@ package main
 
 import (
 	"strconv"
+	"time"
 )
 
 // parseUint8 parses a string and converts it to uint8
@@ -30,6 +31,17 @@ func parseUint8(s string) (uint8, error) {
 	return uint8(u), nil
 }
 
+// parseUint16 parses a string and converts it to uint16
+func parseUint16(s string) (uint16, error) {
+	u, err := strconv.ParseUint(s, 10, 16)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return uint16(u), nil
+}
+
 // parseUint32 parses a string and converts it to uint32
 func parseUint32(s string) (uint32, error) {
 	u, err := strconv.ParseUint(s, 10, 32)
@@ -43,7 +55,7 @@ func parseUint32(s string) (uint32, error) {
 
 // parseInt64 parses a string and converts it to int64
 func parseInt64(s string) (int64, error) {
-	i, err := strconv.ParseInt(s, 10, 32)
+	i, err := strconv.ParseInt(s, 10, 64)
 
 	if err != nil {
 		return 0, err
@@ -52,6 +64,17 @@ func parseInt64(s string) (int64, error) {
 	return int64(i), nil
 }
 
+// parseClock parses a HH:MM time and returns it normalized
+func parseClock(s string) (string, error) {
+	t, err := time.Parse("15:04", s)
+
+	if err != nil {
+		return "", err
+	}
+
+	return t.Format("15:04"), nil
+}
+
 // maxUint32 returns the maximum value from two uint32 values
 func maxUint32(x, y uint32) uint32 {
 	if x < y {
@@ -0,0 +1,197 @@
+// Copyright (c) 2020 Sergio Conde skgsergio@gmail.com
+//
+// This program is free software: you can redistribute it and/or modify it under
+// the terms of the GNU General Public License as published by the Free Software
+// Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT ANY
+// WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A
+// PARTICULAR PURPOSE. See the GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License along with
+// this program. If not, see <https://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"time"
+)
+
+// UserWeek represents the prices recorded by an User in a Group during a week and its forecast
+type UserWeek struct {
+	Times       [12]time.Time
+	Prices      [12]uint32
+	IslandPrice *IslandPrice
+	Forecast    *Forecast
+
+	PreviousForecast *Forecast
+}
+
+// newUserWeekPrices returns an UserWeek with the prices and island price of the week the time belongs to, without forecast
+func newUserWeekPrices(u *User, g *Group, t time.Time) (*UserWeek, error) {
+	uw := UserWeek{}
+
+	// Get now config with group timezone
+	nowCfg, err := g.NowConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get prices and island price
+	prices, err := db.getUserWeekPrices(u, g, t)
+	if err != nil {
+		return nil, err
+	}
+
+	uw.IslandPrice, err = db.getUserIslandPrice(u, g, t)
+	if err != nil {
+		return nil, err
+	}
+
+	// Craft data to have a good looking graph when data is missing
+	initDate := nowCfg.With(t.In(nowCfg.TimeLocation)).BeginningOfWeek().Add(time.Hour * 24)
+
+	for i := 0; i < 12; i++ {
+		uw.Times[i] = initDate.Add(time.Hour * 12 * time.Duration(i))
+	}
+
+	for _, price := range prices {
+		for i := range uw.Times {
+			if price.Date.Equal(uw.Times[i]) {
+				uw.Prices[i] = price.Bells
+				break
+			}
+		}
+	}
+
+	return &uw, nil
+}
+
+// NewUserWeek returns the UserWeek of the week the time belongs to, the forecast is nil if the island price is unknown
+func NewUserWeek(u *User, g *Group, t time.Time) (*UserWeek, error) {
+	uw, err := newUserWeekPrices(u, g, t)
+	if err != nil {
+		return nil, err
+	}
+
+	if !uw.HasIslandPrice() {
+		return uw, nil
+	}
+
+	// Get last week forecast in order to be more accurate
+	var pwForecast *Forecast = nil
+
+	pwTime := t.AddDate(0, 0, -7)
+
+	pwKnownPattern, err := db.getUserKnownPattern(u, g, pwTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// If the user told us last week pattern we trust it, otherwise if last week there were
+	// no prices or no island price we skip the last week forecast
+	if !db.DB.NewRecord(pwKnownPattern) {
+		pwForecast = NewKnownForecast(pwKnownPattern.Pattern)
+	} else {
+		pw, errp := newUserWeekPrices(u, g, pwTime)
+		if errp != nil {
+			return nil, errp
+		}
+
+		if pw.HasPrices() && pw.HasIslandPrice() {
+			pwForecast, err = NewForecast(pw.IslandPrice.Bells, pw.Prices, nil, pw.IslandPrice.FirstBuy)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Get this week forecast
+	uw.PreviousForecast = pwForecast
+
+	uw.Forecast, err = NewForecast(uw.IslandPrice.Bells, uw.Prices, pwForecast, uw.IslandPrice.FirstBuy)
+	if err != nil {
+		return nil, err
+	}
+
+	return uw, nil
+}
+
+// HasPrices returns if any price was recorded during the week
+func (uw *UserWeek) HasPrices() bool {
+	for _, price := range uw.Prices {
+		if price != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasIslandPrice returns if the island price is known
+func (uw *UserWeek) HasIslandPrice() bool {
+	return uw.IslandPrice != nil && uw.IslandPrice.Bells > 0
+}
+
+// HalfDay returns the index of the half day the time belongs to, -1 if it is before the week market opens and 12 if after
+func (uw *UserWeek) HalfDay(t time.Time) int {
+	if t.Before(uw.Times[0]) {
+		return -1
+	}
+
+	for i := range uw.Times {
+		if t.Before(uw.Times[i].Add(time.Hour * 12)) {
+			return i
+		}
+	}
+
+	return len(uw.Times)
+}
+
+// PossiblePrice returns if a price in the half day the time belongs to fits any pattern the forecast still considers,
+// without a forecast or with every pattern already discarded any price is considered possible
+func (uw *UserWeek) PossiblePrice(t time.Time, bells uint32) (bool, error) {
+	if uw.Forecast == nil || len(uw.Forecast.Patterns) == 0 {
+		return true, nil
+	}
+
+	halfDay := uw.HalfDay(t)
+	if halfDay < 0 || halfDay >= len(uw.Prices) {
+		return true, nil
+	}
+
+	prices := uw.Prices
+	prices[halfDay] = bells
+
+	// Previous week only changes the pattern chances, not which patterns are possible
+	f, err := NewForecast(uw.IslandPrice.Bells, prices, nil, uw.IslandPrice.FirstBuy)
+	if err != nil {
+		return false, err
+	}
+
+	return len(f.Patterns) > 0, nil
+}
+
+// TurnipProphetURL returns a link to Turnip Prophet with the week prices, the previous pattern is only set if certain
+func (uw *UserWeek) TurnipProphetURL() string {
+	var (
+		islandPrice uint32
+		firstBuy    bool
+	)
+
+	if uw.HasIslandPrice() {
+		islandPrice = uw.IslandPrice.Bells
+		firstBuy = uw.IslandPrice.FirstBuy
+	}
+
+	previous := -1
+	if uw.PreviousForecast != nil {
+		if pattern, ok := uw.PreviousForecast.CertainPattern(); ok {
+			previous = int(pattern)
+		}
+	}
+
+	return turnipProphetURL(islandPrice, uw.Prices, firstBuy, previous)
+}